@@ -0,0 +1,231 @@
+// Package autoorient rotates and flips decoded images according to their EXIF orientation,
+// so they are displayed the right way up by software which ignores the orientation tag.
+package autoorient
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"io"
+	"strconv"
+
+	"github.com/kolesa-team/goexiv"
+)
+
+// EncodeFunc writes an image in some format, e.g. png.Encode or a closure over jpeg.Encode
+type EncodeFunc func(w io.Writer, img image.Image) error
+
+// Apply returns the image transformed according to the orientation.
+// The image is returned as is for OrientationNormal and invalid orientations.
+// The result is an *image.NRGBA for *image.NRGBA images and an *image.RGBA for any other image.
+func Apply(img image.Image, o goexiv.Orientation) image.Image {
+	if !o.IsValid() || o == goexiv.OrientationNormal {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	rect := image.Rect(0, 0, width, height)
+	if o.SwapsDimensions() {
+		rect = image.Rect(0, 0, height, width)
+	}
+
+	switch src := img.(type) {
+	case *image.NRGBA:
+		dst := image.NewNRGBA(rect)
+		transformPixels(o, dst.Pix, dst.Stride, src.Pix, src.PixOffset(bounds.Min.X, bounds.Min.Y), src.Stride, width, height)
+		return dst
+	case *image.RGBA:
+		dst := image.NewRGBA(rect)
+		transformPixels(o, dst.Pix, dst.Stride, src.Pix, src.PixOffset(bounds.Min.X, bounds.Min.Y), src.Stride, width, height)
+		return dst
+	default:
+		// draw converts the common formats, e.g. *image.YCbCr of JPEG images, without going through color.Color
+		rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+		return Apply(rgba, o)
+	}
+}
+
+// transformPixels copies the 4-byte pixels of a width x height source to dst, transformed according to the orientation
+func transformPixels(o goexiv.Orientation, dst []byte, dstStride int, src []byte, srcOffset, srcStride, width, height int) {
+	for y := 0; y < height; y++ {
+		row := srcOffset + y*srcStride
+		for x := 0; x < width; x++ {
+			dx, dy := transform(o, x, y, width, height)
+			copy(dst[dy*dstStride+dx*4:dy*dstStride+dx*4+4], src[row+x*4:row+x*4+4])
+		}
+	}
+}
+
+// transform maps a pixel of the stored image to the displayed image
+func transform(o goexiv.Orientation, x, y, width, height int) (int, int) {
+	switch o {
+	case goexiv.OrientationFlipHorizontal:
+		return width - 1 - x, y
+	case goexiv.OrientationRotate180:
+		return width - 1 - x, height - 1 - y
+	case goexiv.OrientationFlipVertical:
+		return x, height - 1 - y
+	case goexiv.OrientationTranspose:
+		return y, x
+	case goexiv.OrientationRotate90:
+		return height - 1 - y, x
+	case goexiv.OrientationTransverse:
+		return height - 1 - y, width - 1 - x
+	case goexiv.OrientationRotate270:
+		return y, width - 1 - x
+	default:
+		return x, y
+	}
+}
+
+// Bytes decodes an image, applies its orientation and encodes the result with encode.
+// The metadata of the original image is copied to the result with a single write: the orientation is reset
+// to normal, the EXIF thumbnail is removed and, if the image is rotated by 90 or 270 degrees,
+// Exif.Photo.PixelXDimension and PixelYDimension are swapped.
+// The decoders of the expected formats must be registered, e.g. by importing image/jpeg.
+func Bytes(data []byte, encode EncodeFunc) ([]byte, error) {
+	src, err := goexiv.OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	if err := src.ReadMetadata(); err != nil {
+		return nil, err
+	}
+
+	orientation, err := src.Orientation()
+	if err != nil {
+		// an invalid orientation is treated like a missing one, as image viewers do
+		orientation = goexiv.OrientationNormal
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, Apply(img, orientation)); err != nil {
+		return nil, err
+	}
+
+	dst, err := goexiv.OpenBytes(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer dst.Close()
+
+	if err := copyMetadata(dst, src, orientation); err != nil {
+		return nil, err
+	}
+
+	return dst.GetBytes(), nil
+}
+
+const (
+	pixelXDimension = "Exif.Photo.PixelXDimension"
+	pixelYDimension = "Exif.Photo.PixelYDimension"
+)
+
+// copyMetadata copies the metadata of src to its transformed version dst and updates it
+// to the transformation, with a single metadata write
+func copyMetadata(dst, src *goexiv.Image, o goexiv.Orientation) error {
+	tx, err := dst.Edit()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.CopyMetadata(src, goexiv.CopyAllMetadata); err != nil {
+		return err
+	}
+
+	if dst.Supports(goexiv.EXIF, goexiv.AccessWrite) {
+		if err := tx.SetExifShort("Exif.Image.Orientation", strconv.Itoa(int(goexiv.OrientationNormal))); err != nil {
+			return err
+		}
+
+		if o != goexiv.OrientationNormal {
+			// the thumbnail still shows the image as it was stored
+			if err := tx.EraseExifThumbnail(); err != nil {
+				return err
+			}
+		}
+
+		if o.SwapsDimensions() {
+			if err := swapPixelDimensions(tx, src); err != nil {
+				return err
+			}
+		}
+	}
+
+	if dst.Supports(goexiv.XMP, goexiv.AccessWrite) && hasXmpOrientation(src) {
+		if err := tx.SetXmpString("Xmp.tiff.Orientation", strconv.Itoa(int(goexiv.OrientationNormal))); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// swapPixelDimensions stages the values of Exif.Photo.PixelXDimension and PixelYDimension of src
+// exchanged, since the width and height of a rotated image are swapped
+func swapPixelDimensions(tx *goexiv.MetadataTx, src *goexiv.Image) error {
+	data := src.GetExifData()
+	defer data.Close()
+
+	x, hasX, err := pixelDimension(data, pixelXDimension)
+	if err != nil {
+		return err
+	}
+
+	y, hasY, err := pixelDimension(data, pixelYDimension)
+	if err != nil {
+		return err
+	}
+
+	if !hasX && !hasY {
+		return nil
+	}
+
+	if err := setPixelDimension(tx, pixelXDimension, y, hasY); err != nil {
+		return err
+	}
+
+	return setPixelDimension(tx, pixelYDimension, x, hasX)
+}
+
+func hasXmpOrientation(img *goexiv.Image) bool {
+	data := img.GetXmpData()
+	defer data.Close()
+
+	_, err := data.GetString("Xmp.tiff.Orientation")
+	return err == nil
+}
+
+func pixelDimension(data *goexiv.ExifData, key string) (int64, bool, error) {
+	datum, err := data.FindKey(key)
+	if err != nil || datum == nil {
+		return 0, false, err
+	}
+	defer datum.Close()
+
+	value, err := datum.Int64(0)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return value, true, nil
+}
+
+func setPixelDimension(tx *goexiv.MetadataTx, key string, value int64, ok bool) error {
+	if !ok {
+		return tx.StripKey(goexiv.EXIF, key)
+	}
+
+	return tx.SetExifLong(key, strconv.FormatInt(value, 10))
+}
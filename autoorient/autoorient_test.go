@@ -0,0 +1,126 @@
+package autoorient_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/kolesa-team/goexiv"
+	"github.com/kolesa-team/goexiv/autoorient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	// a 2x1 image with a red pixel on the left and a blue one on the right
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	assert.True(t, autoorient.Apply(img, goexiv.OrientationNormal) == image.Image(img))
+
+	flipped := autoorient.Apply(img, goexiv.OrientationFlipHorizontal)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), flipped.Bounds())
+	assert.Equal(t, blue, flipped.At(0, 0))
+	assert.Equal(t, red, flipped.At(1, 0))
+
+	rotated := autoorient.Apply(img, goexiv.OrientationRotate90)
+	assert.Equal(t, image.Rect(0, 0, 1, 2), rotated.Bounds())
+	assert.Equal(t, red, rotated.At(0, 0))
+	assert.Equal(t, blue, rotated.At(0, 1))
+
+	rotated = autoorient.Apply(img, goexiv.OrientationRotate270)
+	assert.Equal(t, image.Rect(0, 0, 1, 2), rotated.Bounds())
+	assert.Equal(t, blue, rotated.At(0, 0))
+	assert.Equal(t, red, rotated.At(0, 1))
+
+	// the pixels of a sub-image start at its bounds
+	wide := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	wide.Set(1, 0, red)
+	wide.Set(2, 0, blue)
+	flipped = autoorient.Apply(wide.SubImage(image.Rect(1, 0, 3, 1)), goexiv.OrientationFlipHorizontal)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), flipped.Bounds())
+	assert.Equal(t, blue, flipped.At(0, 0))
+	assert.Equal(t, red, flipped.At(1, 0))
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	rgba.Set(0, 0, red)
+	rgba.Set(1, 0, blue)
+	rotated = autoorient.Apply(rgba, goexiv.OrientationRotate90)
+	require.IsType(t, &image.RGBA{}, rotated)
+	assert.Equal(t, image.Rect(0, 0, 1, 2), rotated.Bounds())
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, rotated.At(0, 0))
+	assert.Equal(t, color.RGBA{B: 255, A: 255}, rotated.At(0, 1))
+
+	// other formats are converted to RGBA
+	gray := image.NewGray(image.Rect(0, 0, 2, 1))
+	gray.SetGray(1, 0, color.Gray{Y: 255})
+	flipped = autoorient.Apply(gray, goexiv.OrientationFlipHorizontal)
+	require.IsType(t, &image.RGBA{}, flipped)
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, flipped.At(0, 0))
+	assert.Equal(t, color.RGBA{A: 255}, flipped.At(1, 0))
+
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 2, 1), image.YCbCrSubsampleRatio444)
+	rotated = autoorient.Apply(ycbcr, goexiv.OrientationRotate270)
+	require.IsType(t, &image.RGBA{}, rotated)
+	assert.Equal(t, image.Rect(0, 0, 1, 2), rotated.Bounds())
+}
+
+func TestBytes(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	src, err := goexiv.OpenBytes(data)
+	require.NoError(t, err)
+	require.NoError(t, src.SetOrientation(goexiv.OrientationRotate90))
+	require.NoError(t, src.SetExifString("Exif.Image.Make", "FakeMake"))
+
+	tx, err := src.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetExifLong("Exif.Photo.PixelXDimension", "10"))
+	require.NoError(t, tx.SetExifLong("Exif.Photo.PixelYDimension", "20"))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, src.SetExifThumbnail(data))
+
+	result, err := autoorient.Bytes(src.GetBytes(), func(w io.Writer, img image.Image) error {
+		return png.Encode(w, img)
+	})
+	require.NoError(t, err)
+
+	_, format, err := image.Decode(bytes.NewReader(result))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+
+	dst, err := goexiv.OpenBytes(result)
+	require.NoError(t, err)
+	require.NoError(t, dst.ReadMetadata())
+
+	orientation, err := dst.Orientation()
+	require.NoError(t, err)
+	assert.Equal(t, goexiv.OrientationNormal, orientation)
+
+	value, err := dst.GetExifData().GetString("Exif.Image.Make")
+	require.NoError(t, err)
+	assert.Equal(t, "FakeMake", value)
+
+	// the thumbnail isn't rotated along with the image
+	thumbnail, _, err := dst.ExifThumbnail()
+	require.NoError(t, err)
+	assert.Nil(t, thumbnail)
+
+	// the image is rotated by 90 degrees, so its width and height are swapped
+	value, err = dst.GetExifData().GetString("Exif.Photo.PixelXDimension")
+	require.NoError(t, err)
+	assert.Equal(t, "20", value)
+
+	value, err = dst.GetExifData().GetString("Exif.Photo.PixelYDimension")
+	require.NoError(t, err)
+	assert.Equal(t, "10", value)
+}
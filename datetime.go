@@ -0,0 +1,431 @@
+package goexiv
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	exifDateTimeLayout = "2006:01:02 15:04:05"
+	iptcDateLayout     = "2006-01-02"
+	iptcTimeLayout     = "15:04:05-07:00"
+	xmpDateTimeLayout  = "2006-01-02T15:04:05.999999999-07:00"
+)
+
+// xmpDateTimeLayouts are the ISO 8601 forms allowed in XMP, from the most to the least precise.
+// Fractional seconds are accepted by time.Parse without being in the layout.
+var xmpDateTimeLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// timestamp is a date and time read from a metadata key. When zoned is false,
+// the time has no known offset and is stored as if it were UTC.
+type timestamp struct {
+	time  time.Time
+	zoned bool
+}
+
+// exifDateKeys are the EXIF date and time tags along with the tags holding their sub-seconds
+var exifDateKeys = []struct{ key, subSecondsKey string }{
+	{"Exif.Image.DateTime", "Exif.Photo.SubSecTime"},
+	{"Exif.Photo.DateTimeOriginal", "Exif.Photo.SubSecTimeOriginal"},
+	{"Exif.Photo.DateTimeDigitized", "Exif.Photo.SubSecTimeDigitized"},
+}
+
+// iptcDateKeys are the IPTC date datasets along with the datasets holding their time
+var iptcDateKeys = []struct{ dateKey, timeKey string }{
+	{"Iptc.Application2.DateCreated", "Iptc.Application2.TimeCreated"},
+	{"Iptc.Application2.DigitalCreationDate", "Iptc.Application2.DigitalCreationTime"},
+}
+
+var xmpDateKeys = []string{
+	"Xmp.exif.DateTimeOriginal",
+	"Xmp.exif.DateTimeDigitized",
+	"Xmp.photoshop.DateCreated",
+	"Xmp.tiff.DateTime",
+	"Xmp.xmp.CreateDate",
+	"Xmp.xmp.ModifyDate",
+	"Xmp.xmp.MetadataDate",
+}
+
+// ShiftDatesOptions selects the date tags changed by Image.ShiftDates
+type ShiftDatesOptions struct {
+	// EXIF shifts Exif.Image.DateTime, Exif.Photo.DateTimeOriginal and Exif.Photo.DateTimeDigitized
+	// along with their sub-seconds
+	EXIF bool
+	// GPS shifts Exif.GPSInfo.GPSDateStamp and Exif.GPSInfo.GPSTimeStamp
+	GPS bool
+	// IPTC shifts Iptc.Application2.DateCreated, Iptc.Application2.DigitalCreationDate and their times
+	IPTC bool
+	// XMP shifts the xmp, exif, tiff and photoshop namespace dates
+	XMP bool
+}
+
+// ShiftAllDates shifts every supported date tag
+var ShiftAllDates = ShiftDatesOptions{
+	EXIF: true,
+	GPS:  true,
+	IPTC: true,
+	XMP:  true,
+}
+
+// CaptureTime returns the time the image was taken at. The sources are used in the following order:
+//   - Exif.Photo.DateTimeOriginal with Exif.Photo.OffsetTimeOriginal and Exif.Photo.SubSecTimeOriginal
+//   - Xmp.exif.DateTimeOriginal, Xmp.photoshop.DateCreated and Xmp.xmp.CreateDate
+//   - Iptc.Application2.DateCreated with Iptc.Application2.TimeCreated
+//
+// If the chosen source has no timezone offset or sub-seconds, they are taken from another
+// source which has the same date and time. Without any offset the time is in time.Local.
+// ReadMetadata() must be called before.
+func (i *Image) CaptureTime() (time.Time, bool) {
+	var candidates []timestamp
+
+	exifData := i.GetExifData()
+	defer exifData.Close()
+
+	if t, ok := exifCaptureTime(exifData); ok {
+		candidates = append(candidates, t)
+	}
+
+	xmpData := i.GetXmpData()
+	defer xmpData.Close()
+
+	for _, key := range []string{"Xmp.exif.DateTimeOriginal", "Xmp.photoshop.DateCreated", "Xmp.xmp.CreateDate"} {
+		if value, err := xmpData.GetString(key); err == nil {
+			if t, ok := parseXmpDateTime(value); ok {
+				candidates = append(candidates, t)
+			}
+		}
+	}
+
+	iptcData := i.GetIptcData()
+	defer iptcData.Close()
+
+	if t, ok := iptcCaptureTime(iptcData); ok {
+		candidates = append(candidates, t)
+	}
+
+	if len(candidates) == 0 {
+		return time.Time{}, false
+	}
+
+	result := candidates[0]
+	for _, other := range candidates[1:] {
+		if !sameSecond(result.time, other.time) {
+			continue
+		}
+
+		if result.time.Nanosecond() == 0 && other.time.Nanosecond() != 0 {
+			result.time = result.time.Add(time.Duration(other.time.Nanosecond()))
+		}
+
+		if !result.zoned && other.zoned {
+			result.time = inLocation(result.time, other.time.Location())
+			result.zoned = true
+		}
+	}
+
+	if !result.zoned {
+		return inLocation(result.time, time.Local), true
+	}
+
+	return result.time, true
+}
+
+// SetCaptureTime writes the time the image was taken at to all the keys read by CaptureTime,
+// except Xmp.xmp.CreateDate, with a single metadata write
+func (i *Image) SetCaptureTime(t time.Time) error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.SetCaptureTime(t)
+	})
+}
+
+// SetCaptureTime stages the time the image was taken at to all the keys read by Image.CaptureTime,
+// except Xmp.xmp.CreateDate
+func (t *MetadataTx) SetCaptureTime(value time.Time) error {
+	exifValues := []typedValue{
+		{TypeAsciiString, "Exif.Photo.DateTimeOriginal", value.Format(exifDateTimeLayout)},
+		{TypeAsciiString, "Exif.Photo.OffsetTimeOriginal", value.Format("-07:00")},
+	}
+
+	if value.Nanosecond() == 0 {
+		if err := t.StripKey(EXIF, "Exif.Photo.SubSecTimeOriginal"); err != nil {
+			return err
+		}
+	} else {
+		exifValues = append(exifValues, typedValue{TypeAsciiString, "Exif.Photo.SubSecTimeOriginal", formatSubSeconds(value)})
+	}
+
+	if err := t.setValues("SetCaptureTime", EXIF, exifValues); err != nil {
+		return err
+	}
+
+	if err := t.setValues("SetCaptureTime", IPTC, []typedValue{
+		{TypeDate, "Iptc.Application2.DateCreated", value.Format(iptcDateLayout)},
+		{TypeTime, "Iptc.Application2.TimeCreated", value.Format(iptcTimeLayout)},
+	}); err != nil {
+		return err
+	}
+
+	return t.setValues("SetCaptureTime", XMP, []typedValue{
+		{TypeXmpText, "Xmp.exif.DateTimeOriginal", value.Format(xmpDateTimeLayout)},
+		{TypeXmpText, "Xmp.photoshop.DateCreated", value.Format(xmpDateTimeLayout)},
+	})
+}
+
+// ShiftDates adds d to every date and time tag selected by opts, with a single metadata write,
+// e.g. to fix the dates of a camera with a wrong clock. Timezone offsets are kept as is;
+// the values which can't be parsed are skipped. ReadMetadata() must be called before.
+func (i *Image) ShiftDates(d time.Duration, opts ShiftDatesOptions) error {
+	if err := i.checkImage(); err != nil {
+		return err
+	}
+
+	var exifValues, iptcValues, xmpValues []typedValue
+
+	if opts.EXIF || opts.GPS {
+		exifData := i.GetExifData()
+		defer exifData.Close()
+
+		if opts.EXIF {
+			exifValues = append(exifValues, shiftExifDates(exifData, d)...)
+		}
+
+		if opts.GPS {
+			exifValues = append(exifValues, shiftGPSDate(exifData, d)...)
+		}
+	}
+
+	if opts.IPTC {
+		iptcData := i.GetIptcData()
+		defer iptcData.Close()
+
+		iptcValues = shiftIptcDates(iptcData, d)
+	}
+
+	if opts.XMP {
+		xmpData := i.GetXmpData()
+		defer xmpData.Close()
+
+		xmpValues = shiftXmpDates(xmpData, d)
+	}
+
+	if len(exifValues) == 0 && len(iptcValues) == 0 && len(xmpValues) == 0 {
+		return nil
+	}
+
+	return i.edit(func(tx *MetadataTx) error {
+		if err := tx.setValues("ShiftDates", EXIF, exifValues); err != nil {
+			return err
+		}
+
+		if err := tx.setValues("ShiftDates", IPTC, iptcValues); err != nil {
+			return err
+		}
+
+		return tx.setValues("ShiftDates", XMP, xmpValues)
+	})
+}
+
+func shiftExifDates(data *ExifData, d time.Duration) []typedValue {
+	var values []typedValue
+
+	for _, keys := range exifDateKeys {
+		value, err := data.GetString(keys.key)
+		if err != nil {
+			continue
+		}
+
+		subSeconds, subSecondsErr := data.GetString(keys.subSecondsKey)
+
+		t, ok := parseExifDateTime(value, subSeconds, "")
+		if !ok {
+			continue
+		}
+
+		shifted := t.time.Add(d)
+		values = append(values, typedValue{TypeAsciiString, keys.key, shifted.Format(exifDateTimeLayout)})
+
+		if subSecondsErr == nil || shifted.Nanosecond() != 0 {
+			values = append(values, typedValue{TypeAsciiString, keys.subSecondsKey, formatSubSeconds(shifted)})
+		}
+	}
+
+	return values
+}
+
+func shiftGPSDate(data *ExifData, d time.Duration) []typedValue {
+	t, err := data.gpsTimestamp()
+	if err != nil || t == nil {
+		return nil
+	}
+
+	return gpsTimestampValues(t.Add(d))
+}
+
+func shiftIptcDates(data *IptcData, d time.Duration) []typedValue {
+	var values []typedValue
+
+	for _, keys := range iptcDateKeys {
+		t, ok := iptcDateTime(data, keys.dateKey, keys.timeKey)
+		if !ok {
+			continue
+		}
+
+		shifted := t.time.Add(d)
+		values = append(values, typedValue{TypeDate, keys.dateKey, shifted.Format(iptcDateLayout)})
+
+		if t.zoned {
+			values = append(values, typedValue{TypeTime, keys.timeKey, shifted.Format(iptcTimeLayout)})
+		}
+	}
+
+	return values
+}
+
+func shiftXmpDates(data *XmpData, d time.Duration) []typedValue {
+	var values []typedValue
+
+	for _, key := range xmpDateKeys {
+		value, err := data.GetString(key)
+		if err != nil {
+			continue
+		}
+
+		t, layout, ok := parseXmpDateTimeLayout(value)
+		if !ok {
+			continue
+		}
+
+		switch layout {
+		case "2006-01-02T15:04:05Z07:00":
+			layout = xmpDateTimeLayout
+		case "2006-01-02T15:04:05":
+			layout = "2006-01-02T15:04:05.999999999"
+		}
+
+		values = append(values, typedValue{TypeXmpText, key, t.time.Add(d).Format(layout)})
+	}
+
+	return values
+}
+
+func exifCaptureTime(d *ExifData) (timestamp, bool) {
+	value, err := d.GetString("Exif.Photo.DateTimeOriginal")
+	if err != nil {
+		return timestamp{}, false
+	}
+
+	subSeconds, _ := d.GetString("Exif.Photo.SubSecTimeOriginal")
+	offset, _ := d.GetString("Exif.Photo.OffsetTimeOriginal")
+
+	return parseExifDateTime(value, subSeconds, offset)
+}
+
+func iptcCaptureTime(d *IptcData) (timestamp, bool) {
+	return iptcDateTime(d, "Iptc.Application2.DateCreated", "Iptc.Application2.TimeCreated")
+}
+
+// iptcDateTime combines a date dataset with its time dataset, which may be missing
+func iptcDateTime(d *IptcData, dateKey, timeKey string) (timestamp, bool) {
+	date, err := d.GetString(dateKey)
+	if err != nil {
+		return timestamp{}, false
+	}
+
+	day, err := time.Parse(iptcDateLayout, strings.TrimSpace(date))
+	if err != nil {
+		return timestamp{}, false
+	}
+
+	clock, err := d.GetString(timeKey)
+	if err != nil {
+		return timestamp{day, false}, true
+	}
+
+	t, err := time.Parse(iptcTimeLayout, strings.TrimSpace(clock))
+	if err != nil {
+		return timestamp{day, false}, true
+	}
+
+	return timestamp{
+		time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location()),
+		true,
+	}, true
+}
+
+// parseExifDateTime parses an EXIF date and time along with its sub-seconds and offset tags,
+// both of which may be empty
+func parseExifDateTime(value, subSeconds, offset string) (timestamp, bool) {
+	t, err := time.Parse(exifDateTimeLayout, strings.TrimSpace(value))
+	if err != nil {
+		return timestamp{}, false
+	}
+
+	t = t.Add(parseSubSeconds(subSeconds))
+
+	if zone, err := time.Parse("-07:00", strings.TrimSpace(offset)); err == nil {
+		return timestamp{inLocation(t, zone.Location()), true}, true
+	}
+
+	return timestamp{t, false}, true
+}
+
+func parseXmpDateTime(value string) (timestamp, bool) {
+	t, _, ok := parseXmpDateTimeLayout(value)
+
+	return t, ok
+}
+
+// parseXmpDateTimeLayout also returns the layout the value matched, so it can be written back with the same precision
+func parseXmpDateTimeLayout(value string) (timestamp, string, bool) {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range xmpDateTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return timestamp{t, strings.HasSuffix(layout, "Z07:00")}, layout, true
+		}
+	}
+
+	return timestamp{}, "", false
+}
+
+// parseSubSeconds converts the digits of a decimal fraction of a second, e.g. "25" for 0.25s
+func parseSubSeconds(value string) time.Duration {
+	value = strings.TrimSpace(value)
+
+	var result time.Duration
+	for n, scale := 0, time.Second/10; n < len(value) && scale > 0; n, scale = n+1, scale/10 {
+		if value[n] < '0' || value[n] > '9' {
+			return 0
+		}
+		result += time.Duration(value[n]-'0') * scale
+	}
+
+	return result
+}
+
+func formatSubSeconds(t time.Time) string {
+	if t.Nanosecond() == 0 {
+		return "0"
+	}
+
+	return strings.TrimRight(fmt.Sprintf("%09d", t.Nanosecond()), "0")
+}
+
+// inLocation returns the same wall clock time in another location
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+func sameSecond(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay() &&
+		a.Hour() == b.Hour() && a.Minute() == b.Minute() && a.Second() == b.Second()
+}
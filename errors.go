@@ -0,0 +1,254 @@
+package goexiv
+
+// #cgo pkg-config: exiv2
+// #include "helper.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrorCode is the code of an Error. The codes below 1000 mirror Exiv2::ErrorCode of libexiv2 v0.27,
+// the rest are reported by goexiv itself.
+//
+// ErrorCode implements error, so a code can be checked with errors.Is(err, goexiv.ErrorCodeInvalidKey).
+type ErrorCode int
+
+const (
+	ErrorCodeGeneral                                 ErrorCode = -1
+	ErrorCodeSuccess                                 ErrorCode = 0
+	ErrorCodeErrorMessage                            ErrorCode = 1
+	ErrorCodeCallFailed                              ErrorCode = 2
+	ErrorCodeNotAnImage                              ErrorCode = 3
+	ErrorCodeInvalidDataset                          ErrorCode = 4
+	ErrorCodeInvalidRecord                           ErrorCode = 5
+	ErrorCodeInvalidKey                              ErrorCode = 6
+	ErrorCodeInvalidTag                              ErrorCode = 7
+	ErrorCodeValueNotSet                             ErrorCode = 8
+	ErrorCodeDataSourceOpenFailed                    ErrorCode = 9
+	ErrorCodeFileOpenFailed                          ErrorCode = 10
+	ErrorCodeFileContainsUnknownImageType            ErrorCode = 11
+	ErrorCodeMemoryContainsUnknownImageType          ErrorCode = 12
+	ErrorCodeUnsupportedImageType                    ErrorCode = 13
+	ErrorCodeFailedToReadImageData                   ErrorCode = 14
+	ErrorCodeNotAJpeg                                ErrorCode = 15
+	ErrorCodeFailedToMapFileForReadWrite             ErrorCode = 16
+	ErrorCodeFileRenameFailed                        ErrorCode = 17
+	ErrorCodeTransferFailed                          ErrorCode = 18
+	ErrorCodeMemoryTransferFailed                    ErrorCode = 19
+	ErrorCodeInputDataReadFailed                     ErrorCode = 20
+	ErrorCodeImageWriteFailed                        ErrorCode = 21
+	ErrorCodeNoImageInInputData                      ErrorCode = 22
+	ErrorCodeInvalidIfdId                            ErrorCode = 23
+	ErrorCodeValueTooLarge                           ErrorCode = 24
+	ErrorCodeDataAreaValueTooLarge                   ErrorCode = 25
+	ErrorCodeOffsetOutOfRange                        ErrorCode = 26
+	ErrorCodeUnsupportedDataAreaOffsetType           ErrorCode = 27
+	ErrorCodeInvalidCharset                          ErrorCode = 28
+	ErrorCodeUnsupportedDateFormat                   ErrorCode = 29
+	ErrorCodeUnsupportedTimeFormat                   ErrorCode = 30
+	ErrorCodeWritingImageFormatUnsupported           ErrorCode = 31
+	ErrorCodeInvalidSettingForImage                  ErrorCode = 32
+	ErrorCodeNotACrwImage                            ErrorCode = 33
+	ErrorCodeFunctionNotSupported                    ErrorCode = 34
+	ErrorCodeNoNamespaceInfoForXmpPrefix             ErrorCode = 35
+	ErrorCodeNoPrefixForNamespace                    ErrorCode = 36
+	ErrorCodeTooLargeJpegSegment                     ErrorCode = 37
+	ErrorCodeUnhandledXmpdatum                       ErrorCode = 38
+	ErrorCodeUnhandledXmpNode                        ErrorCode = 39
+	ErrorCodeXMPToolkitError                         ErrorCode = 40
+	ErrorCodeDecodeLangAltPropertyFailed             ErrorCode = 41
+	ErrorCodeDecodeLangAltQualifierFailed            ErrorCode = 42
+	ErrorCodeEncodeLangAltPropertyFailed             ErrorCode = 43
+	ErrorCodePropertyNameIdentificationFailed        ErrorCode = 44
+	ErrorCodeSchemaNamespaceNotRegistered            ErrorCode = 45
+	ErrorCodeNoNamespaceForPrefix                    ErrorCode = 46
+	ErrorCodeAliasesNotSupported                     ErrorCode = 47
+	ErrorCodeInvalidXmpText                          ErrorCode = 48
+	ErrorCodeTooManyTiffDirectoryEntries             ErrorCode = 49
+	ErrorCodeMultipleTiffArrayElementTagsInDirectory ErrorCode = 50
+	ErrorCodeWrongTiffArrayElementTagType            ErrorCode = 51
+	ErrorCodeInvalidKeyXmpValue                      ErrorCode = 52
+	ErrorCodeInvalidIccProfile                       ErrorCode = 53
+	ErrorCodeInvalidXMP                              ErrorCode = 54
+	ErrorCodeTiffDirectoryTooLarge                   ErrorCode = 55
+	ErrorCodeInvalidTypeValue                        ErrorCode = 56
+	ErrorCodeInvalidLangAltValue                     ErrorCode = 57
+	ErrorCodeInvalidMalloc                           ErrorCode = 58
+	ErrorCodeCorruptedMetadata                       ErrorCode = 59
+	ErrorCodeArithmeticOverflow                      ErrorCode = 60
+	ErrorCodeMallocFailed                            ErrorCode = 61
+
+	// ErrorCodeEmptyInput is reported when an image is opened from empty input
+	ErrorCodeEmptyInput ErrorCode = 1000
+	// ErrorCodeNotInitialized is reported when the Image has not been created by one of the Open functions
+	ErrorCodeNotInitialized ErrorCode = 1001
+	// ErrorCodeInvalidMetadataFormat is reported for an unknown MetadataFormat or format name
+	ErrorCodeInvalidMetadataFormat ErrorCode = 1002
+	// ErrorCodeInputTooLarge is reported when an image is too large to be handled by libexiv2
+	ErrorCodeInputTooLarge ErrorCode = 1003
+	// ErrorCodeStdException is reported when libexiv2 throws a C++ standard exception,
+	// e.g. std::bad_alloc or std::out_of_range on a malformed file
+	ErrorCodeStdException ErrorCode = 1004
+	// ErrorCodeUnknownException is reported when libexiv2 throws something other than a C++ standard exception
+	ErrorCodeUnknownException ErrorCode = 1005
+)
+
+func (c ErrorCode) Error() string {
+	return "exiv2 error code " + strconv.Itoa(int(c))
+}
+
+// Error classes, which group the related error codes. Any Error can be checked against them with errors.Is,
+// e.g. errors.Is(err, goexiv.ErrUnsupportedImageType).
+var (
+	ErrEmptyInput            = errors.New("input is empty")
+	ErrNotInitialized        = errors.New("image instance is not initialized")
+	ErrInvalidMetadataFormat = errors.New("invalid metadata format")
+	ErrDataSourceOpenFailed  = errors.New("failed to open the data source")
+	ErrReadFailed            = errors.New("failed to read the image")
+	ErrWriteFailed           = errors.New("failed to write the image")
+	ErrUnsupportedImageType  = errors.New("unsupported image type")
+	ErrInvalidKey            = errors.New("invalid metadata key")
+	ErrInvalidValue          = errors.New("invalid metadata value")
+	ErrCorruptedMetadata     = errors.New("corrupted metadata")
+	ErrInternal              = errors.New("internal libexiv2 error")
+)
+
+var errorClasses = map[ErrorCode]error{
+	ErrorCodeEmptyInput:                  ErrEmptyInput,
+	ErrorCodeNotInitialized:              ErrNotInitialized,
+	ErrorCodeInvalidMetadataFormat:       ErrInvalidMetadataFormat,
+	ErrorCodeDataSourceOpenFailed:        ErrDataSourceOpenFailed,
+	ErrorCodeFileOpenFailed:              ErrDataSourceOpenFailed,
+	ErrorCodeFailedToReadImageData:       ErrReadFailed,
+	ErrorCodeInputDataReadFailed:         ErrReadFailed,
+	ErrorCodeImageWriteFailed:            ErrWriteFailed,
+	ErrorCodeFileRenameFailed:            ErrWriteFailed,
+	ErrorCodeTransferFailed:              ErrWriteFailed,
+	ErrorCodeMemoryTransferFailed:        ErrWriteFailed,
+	ErrorCodeFailedToMapFileForReadWrite: ErrWriteFailed,
+
+	ErrorCodeNotAnImage:                     ErrUnsupportedImageType,
+	ErrorCodeFileContainsUnknownImageType:   ErrUnsupportedImageType,
+	ErrorCodeMemoryContainsUnknownImageType: ErrUnsupportedImageType,
+	ErrorCodeUnsupportedImageType:           ErrUnsupportedImageType,
+	ErrorCodeNoImageInInputData:             ErrUnsupportedImageType,
+	ErrorCodeWritingImageFormatUnsupported:  ErrUnsupportedImageType,
+	ErrorCodeInvalidSettingForImage:         ErrUnsupportedImageType,
+
+	ErrorCodeInvalidKey:                       ErrInvalidKey,
+	ErrorCodeInvalidTag:                       ErrInvalidKey,
+	ErrorCodeInvalidDataset:                   ErrInvalidKey,
+	ErrorCodeInvalidRecord:                    ErrInvalidKey,
+	ErrorCodeNoNamespaceInfoForXmpPrefix:      ErrInvalidKey,
+	ErrorCodeNoPrefixForNamespace:             ErrInvalidKey,
+	ErrorCodeNoNamespaceForPrefix:             ErrInvalidKey,
+	ErrorCodeSchemaNamespaceNotRegistered:     ErrInvalidKey,
+	ErrorCodePropertyNameIdentificationFailed: ErrInvalidKey,
+
+	ErrorCodeValueNotSet:           ErrInvalidValue,
+	ErrorCodeValueTooLarge:         ErrInvalidValue,
+	ErrorCodeDataAreaValueTooLarge: ErrInvalidValue,
+	ErrorCodeInvalidCharset:        ErrInvalidValue,
+	ErrorCodeUnsupportedDateFormat: ErrInvalidValue,
+	ErrorCodeUnsupportedTimeFormat: ErrInvalidValue,
+	ErrorCodeInvalidXmpText:        ErrInvalidValue,
+	ErrorCodeInvalidKeyXmpValue:    ErrInvalidValue,
+	ErrorCodeInvalidTypeValue:      ErrInvalidValue,
+	ErrorCodeInvalidLangAltValue:   ErrInvalidValue,
+
+	ErrorCodeCorruptedMetadata:                       ErrCorruptedMetadata,
+	ErrorCodeNotAJpeg:                                ErrCorruptedMetadata,
+	ErrorCodeNotACrwImage:                            ErrCorruptedMetadata,
+	ErrorCodeInvalidIfdId:                            ErrCorruptedMetadata,
+	ErrorCodeOffsetOutOfRange:                        ErrCorruptedMetadata,
+	ErrorCodeTooLargeJpegSegment:                     ErrCorruptedMetadata,
+	ErrorCodeTooManyTiffDirectoryEntries:             ErrCorruptedMetadata,
+	ErrorCodeMultipleTiffArrayElementTagsInDirectory: ErrCorruptedMetadata,
+	ErrorCodeWrongTiffArrayElementTagType:            ErrCorruptedMetadata,
+	ErrorCodeTiffDirectoryTooLarge:                   ErrCorruptedMetadata,
+	ErrorCodeInvalidXMP:                              ErrCorruptedMetadata,
+	ErrorCodeInvalidIccProfile:                       ErrCorruptedMetadata,
+	ErrorCodeInvalidMalloc:                           ErrCorruptedMetadata,
+	ErrorCodeArithmeticOverflow:                      ErrCorruptedMetadata,
+	ErrorCodeDecodeLangAltPropertyFailed:             ErrCorruptedMetadata,
+	ErrorCodeDecodeLangAltQualifierFailed:            ErrCorruptedMetadata,
+
+	ErrorCodeStdException:     ErrInternal,
+	ErrorCodeUnknownException: ErrInternal,
+}
+
+// Error is an error reported by libexiv2 or by goexiv. Along with the code it carries
+// the operation which failed and the metadata key or file name it was called with, if any.
+type Error struct {
+	code ErrorCode
+	what string
+	op   string
+	key  string
+}
+
+// Error returns the message prefixed with the operation and the key or file name,
+// e.g. "Open image.jpg: <message>". Errors without an operation return just the message.
+func (e *Error) Error() string {
+	switch {
+	case e.op == "":
+		return e.what
+	case e.key == "":
+		return e.op + ": " + e.what
+	default:
+		return e.op + " " + e.key + ": " + e.what
+	}
+}
+
+// Code returns the numeric error code, see ErrorCode
+func (e *Error) Code() int {
+	return int(e.code)
+}
+
+// ErrorCode returns the error code
+func (e *Error) ErrorCode() ErrorCode {
+	return e.code
+}
+
+// Op returns the name of the failed operation, e.g. "SetMetadataString", or an empty string
+func (e *Error) Op() string {
+	return e.op
+}
+
+// Key returns the metadata key the failed operation was called with, or the file name
+// for the Open functions, e.g. the path given to Open. It is empty if there is neither.
+func (e *Error) Key() string {
+	return e.key
+}
+
+// Is reports whether the error has the target ErrorCode
+func (e *Error) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == e.code
+}
+
+// Unwrap returns the error class of the code, e.g. ErrInvalidKey
+func (e *Error) Unwrap() error {
+	return errorClasses[e.code]
+}
+
+func newError(code ErrorCode, what, op, key string) *Error {
+	return &Error{
+		code: code,
+		what: what,
+		op:   op,
+		key:  key,
+	}
+}
+
+// makeError converts an error of the C API, adding the operation and key context
+func makeError(cerr *C.Exiv2Error, op, key string) *Error {
+	return newError(
+		ErrorCode(C.exiv2_error_code(cerr)),
+		C.GoString(C.exiv2_error_what(cerr)),
+		op,
+		key,
+	)
+}
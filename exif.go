@@ -56,8 +56,50 @@ func makeExifDatum(data *ExifData, cdatum *C.Exiv2ExifDatum) *ExifDatum {
 	return datum
 }
 
+// Close frees the underlying C structure. Datums and iterators obtained from
+// the data can't be used afterwards. It is safe to call Close more than once.
+func (d *ExifData) Close() error {
+	if d.data == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(d, nil)
+	C.exiv2_exif_data_free(d.data)
+	d.data = nil
+
+	return nil
+}
+
+func (d *ExifData) isClosed() bool {
+	return d.data == nil || d.img.img == nil
+}
+
+// Close frees the underlying C structure. It is safe to call Close more than once.
+func (d *ExifDatum) Close() error {
+	if d.datum == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(d, nil)
+	C.exiv2_exif_datum_free(d.datum)
+	d.datum = nil
+
+	return nil
+}
+
+func (d *ExifDatum) isClosed() bool {
+	return d.datum == nil || d.data.isClosed()
+}
+
 func (i *Image) GetExifData() *ExifData {
-	return makeExifData(i, C.exiv2_image_get_exif_data(i.img))
+	if i.img == nil {
+		return &ExifData{img: i}
+	}
+
+	data := makeExifData(i, C.exiv2_image_get_exif_data(i.img))
+	runtime.KeepAlive(i)
+
+	return data
 }
 
 func (i *Image) SetExifString(key, value string) error {
@@ -78,6 +120,10 @@ func (d *ExifData) GetString(key string) (string, error) {
 }
 
 func (d *ExifData) FindKey(key string) (*ExifDatum, error) {
+	if d.isClosed() {
+		return nil, ErrClosed
+	}
+
 	ckey := C.CString(key)
 	defer C.free(unsafe.Pointer(ckey))
 
@@ -86,7 +132,7 @@ func (d *ExifData) FindKey(key string) (*ExifDatum, error) {
 	cdatum := C.exiv2_exif_data_find_key(d.data, ckey, &cerr)
 
 	if cerr != nil {
-		err := makeError(cerr)
+		err := makeError(cerr, "FindKey", key)
 		C.exiv2_error_free(cerr)
 		return nil, err
 	}
@@ -96,22 +142,206 @@ func (d *ExifData) FindKey(key string) (*ExifDatum, error) {
 
 // Key returns the Exif key of the datum.
 func (d *ExifDatum) Key() string {
-	return C.GoString(C.exiv2_exif_datum_key(d.datum))
+	if d.isClosed() {
+		return ""
+	}
+
+	cstr := C.exiv2_exif_datum_key(d.datum)
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
 }
 
 func (d *ExifDatum) String() string {
+	if d.isClosed() {
+		return ""
+	}
+
 	cstr := C.exiv2_exif_datum_to_string(d.datum)
 	defer C.free(unsafe.Pointer(cstr))
 
 	return C.GoString(cstr)
 }
 
+// Interpreted returns the value as printed by exiv2 for humans, e.g. "1/250 s" for Exif.Photo.ExposureTime
+// or "Normal program" for Exif.Photo.ExposureProgram, whereas String returns the raw "1/250" and "2".
+// MakerNote tags, such as the lens type, are decoded with the help of the other tags of the image.
+func (d *ExifDatum) Interpreted() string {
+	if d.isClosed() {
+		return ""
+	}
+
+	cstr := C.exiv2_exif_datum_print(d.datum, d.data.data)
+	runtime.KeepAlive(d)
+
+	if cstr == nil {
+		return d.String()
+	}
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
+
+// TypeID returns the type of the datum value.
+func (d *ExifDatum) TypeID() TypeID {
+	if d.isClosed() {
+		return TypeInvalid
+	}
+
+	result := TypeID(C.exiv2_exif_datum_type_id(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// TypeName returns the name of the datum value type, e.g. "Rational".
+func (d *ExifDatum) TypeName() string {
+	if d.isClosed() {
+		return ""
+	}
+
+	result := C.GoString(C.exiv2_exif_datum_type_name(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Count returns the number of components of the datum value.
+func (d *ExifDatum) Count() int64 {
+	if d.isClosed() {
+		return 0
+	}
+
+	result := int64(C.exiv2_exif_datum_count(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Size returns the size of the datum value in bytes.
+func (d *ExifDatum) Size() int64 {
+	if d.isClosed() {
+		return 0
+	}
+
+	result := int64(C.exiv2_exif_datum_size(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Int64 returns the n-th component of the datum value converted to an integer.
+func (d *ExifDatum) Int64(n int) (int64, error) {
+	if d.isClosed() {
+		return 0, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return 0, err
+	}
+
+	var ok C.int
+	result := int64(C.exiv2_exif_datum_to_long(d.datum, C.long(n), &ok))
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return 0, ErrValueConversion
+	}
+
+	return result, nil
+}
+
+// Float64 returns the n-th component of the datum value converted to a float.
+func (d *ExifDatum) Float64(n int) (float64, error) {
+	if d.isClosed() {
+		return 0, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return 0, err
+	}
+
+	var ok C.int
+	result := float64(C.exiv2_exif_datum_to_double(d.datum, C.long(n), &ok))
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return 0, ErrValueConversion
+	}
+
+	return result, nil
+}
+
+// Rational returns the n-th component of the datum value converted to a fraction.
+func (d *ExifDatum) Rational(n int) (Rational, error) {
+	if d.isClosed() {
+		return Rational{}, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return Rational{}, err
+	}
+
+	var num, den C.longlong
+	ok := C.exiv2_exif_datum_to_rational(d.datum, C.long(n), &num, &den)
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return Rational{}, ErrValueConversion
+	}
+
+	return Rational{int64(num), int64(den)}, nil
+}
+
+// Bytes returns the raw datum value. Multi-byte numbers are encoded in little-endian order.
+func (d *ExifDatum) Bytes() []byte {
+	if d.isClosed() {
+		return nil
+	}
+
+	var size C.long
+	ptr := C.exiv2_exif_datum_to_bytes(d.datum, &size)
+	runtime.KeepAlive(d)
+
+	if ptr == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(ptr))
+
+	return C.GoBytes(unsafe.Pointer(ptr), C.int(size))
+}
+
 // Returns all EXIF tags
 func (d *ExifData) AllTags() map[string]string {
 	keyValues := map[string]string{}
-	for i := d.Iterator(); i.HasNext(); {
-		d := i.Next()
-		keyValues[d.Key()] = d.String()
+	iter := d.Iterator()
+	defer iter.Close()
+
+	for iter.HasNext() {
+		datum := iter.Next()
+		if datum == nil {
+			break
+		}
+		keyValues[datum.Key()] = datum.String()
+		datum.Close()
+	}
+
+	return keyValues
+}
+
+// AllTagsInterpreted returns all EXIF tags with their human-readable values, see ExifDatum.Interpreted
+func (d *ExifData) AllTagsInterpreted() map[string]string {
+	keyValues := map[string]string{}
+	iter := d.Iterator()
+	defer iter.Close()
+
+	for iter.HasNext() {
+		datum := iter.Next()
+		if datum == nil {
+			break
+		}
+		keyValues[datum.Key()] = datum.Interpreted()
+		datum.Close()
 	}
 
 	return keyValues
@@ -119,16 +349,28 @@ func (d *ExifData) AllTags() map[string]string {
 
 // Iterator returns a new ExifDatumIterator to iterate over all Exif data.
 func (d *ExifData) Iterator() *ExifDatumIterator {
+	if d.isClosed() {
+		return &ExifDatumIterator{data: d}
+	}
+
 	return makeExifDatumIterator(d, C.exiv2_exif_data_iterator(d.data))
 }
 
 // HasNext returns true as long as the iterator has another datum to deliver.
 func (i *ExifDatumIterator) HasNext() bool {
+	if i.isClosed() {
+		return false
+	}
+
 	return C.exiv2_exif_data_iterator_has_next(i.iter) != 0
 }
 
 // Next returns the next ExifDatum of the iterator or nil if iterator has reached the end.
 func (i *ExifDatumIterator) Next() *ExifDatum {
+	if i.isClosed() {
+		return nil
+	}
+
 	return makeExifDatum(i.data, C.exiv2_exif_datum_iterator_next(i.iter))
 }
 
@@ -142,6 +384,23 @@ func makeExifDatumIterator(data *ExifData, cIter *C.Exiv2ExifDatumIterator) *Exi
 	return datum
 }
 
+// Close frees the underlying C structure. It is safe to call Close more than once.
+func (i *ExifDatumIterator) Close() error {
+	if i.iter == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(i, nil)
+	C.exiv2_exif_datum_iterator_free(i.iter)
+	i.iter = nil
+
+	return nil
+}
+
+func (i *ExifDatumIterator) isClosed() bool {
+	return i.iter == nil || i.data.isClosed()
+}
+
 func (i *Image) ExifStripKey(key string) error {
 	return i.StripKey(EXIF, key)
 }
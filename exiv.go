@@ -7,18 +7,26 @@ import "C"
 
 import (
 	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"runtime"
+	"runtime/cgo"
+	"strings"
+	"syscall"
 	"unsafe"
 )
 
-type Error struct {
-	code int
-	what string
-}
-
 type Image struct {
 	bytesArrayPtr unsafe.Pointer
 	img           *C.Exiv2Image
+	closed        bool
+	// reader references the io.ReaderAt of an image opened with OpenReaderAt
+	reader cgo.Handle
+	closer io.Closer
+	// warnings logged by libexiv2 during the last ReadMetadata call
+	warnings []LogMessage
 }
 
 type MetadataProvider interface {
@@ -31,24 +39,34 @@ const (
 	EXIF MetadataFormat = iota
 	IPTC
 	XMP
+	// COMMENT is the image comment, e.g. the JPEG COM segment
+	COMMENT
+	// ICC is the ICC color profile
+	ICC
 )
 
-var ErrMetadataKeyNotFound = errors.New("key not found")
-
-func (e *Error) Error() string {
-	return e.what
+// metadataIds maps the formats to Exiv2::MetadataId
+var metadataIds = map[MetadataFormat]C.int{
+	EXIF:    1,
+	IPTC:    2,
+	COMMENT: 4,
+	XMP:     8,
+	ICC:     16,
 }
 
-func (e *Error) Code() int {
-	return e.code
-}
+// AccessMode tells whether a kind of metadata can be read or written
+type AccessMode int
 
-func makeError(cerr *C.Exiv2Error) *Error {
-	return &Error{
-		int(C.exiv2_error_code(cerr)),
-		C.GoString(C.exiv2_error_what(cerr)),
-	}
-}
+const (
+	AccessNone      AccessMode = 0
+	AccessRead      AccessMode = 1
+	AccessWrite     AccessMode = 2
+	AccessReadWrite AccessMode = AccessRead | AccessWrite
+)
+
+var ErrMetadataKeyNotFound = errors.New("key not found")
+
+var ErrClosed = errors.New("image or metadata object has been closed")
 
 func makeImage(cimg *C.Exiv2Image, bytesPtr unsafe.Pointer) *Image {
 	img := &Image{
@@ -58,17 +76,63 @@ func makeImage(cimg *C.Exiv2Image, bytesPtr unsafe.Pointer) *Image {
 
 	runtime.SetFinalizer(
 		img, func(x *Image) {
-			C.exiv2_image_free(x.img)
-
-			if x.bytesArrayPtr != nil {
-				C.free(x.bytesArrayPtr)
-			}
+			x.free()
 		},
 	)
 
 	return img
 }
 
+// Close frees the underlying C++ image and the copy of the OpenBytes input right away,
+// instead of waiting for the garbage collector. The metadata objects obtained from
+// the image can't be used after Close. It is safe to call Close more than once.
+func (i *Image) Close() error {
+	if i.closed {
+		return nil
+	}
+
+	runtime.SetFinalizer(i, nil)
+	i.free()
+	i.closed = true
+
+	return nil
+}
+
+func (i *Image) free() {
+	if i.img != nil {
+		C.exiv2_image_free(i.img)
+		i.img = nil
+	}
+
+	if i.bytesArrayPtr != nil {
+		C.free(i.bytesArrayPtr)
+		i.bytesArrayPtr = nil
+	}
+
+	if i.reader != 0 {
+		i.reader.Delete()
+		i.reader = 0
+	}
+
+	if i.closer != nil {
+		i.closer.Close()
+		i.closer = nil
+	}
+}
+
+// checkImage returns an error if the underlying C structure can't be used
+func (i *Image) checkImage() error {
+	if i.closed {
+		return ErrClosed
+	}
+
+	if i.img == nil {
+		return newError(ErrorCodeNotInitialized, "image instance is not initialized: underlying C structure is nil", "", "")
+	}
+
+	return nil
+}
+
 // Open opens an image file from the filesystem and returns a pointer to
 // the corresponding Image object, but does not read the Metadata.
 // Start the parsing with a call to ReadMetadata()
@@ -81,7 +145,7 @@ func Open(path string) (*Image, error) {
 	cimg := C.exiv2_image_factory_open(cpath, &cerr)
 
 	if cerr != nil {
-		err := makeError(cerr)
+		err := makeError(cerr, "Open", path)
 		C.exiv2_error_free(cerr)
 		return nil, err
 	}
@@ -94,25 +158,138 @@ func Open(path string) (*Image, error) {
 // Start the parsing with a call to ReadMetadata()
 func OpenBytes(input []byte) (*Image, error) {
 	if len(input) == 0 {
-		return nil, &Error{0, "input is empty"}
+		return nil, newError(ErrorCodeEmptyInput, "input is empty", "OpenBytes", "")
+	}
+
+	return openCBytes("OpenBytes", "", C.CBytes(input), len(input))
+}
+
+// OpenReader reads an image from r and returns a pointer to the corresponding
+// Image object, but does not read the Metadata. The whole input is read,
+// since exiv2 needs random access to the image.
+func OpenReader(r io.Reader) (*Image, error) {
+	return openReader("OpenReader", "", r)
+}
+
+// openReader reads the whole input of r and opens it, reporting errors as op name
+func openReader(op, name string, r io.Reader) (*Image, error) {
+	ptr, size, err := readCBytes(op, name, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if size == 0 {
+		C.free(ptr)
+		return nil, newError(ErrorCodeEmptyInput, "input is empty", op, name)
+	}
+
+	return openCBytes(op, name, ptr, size)
+}
+
+// readCBytes reads r until EOF into a buffer allocated with C.malloc,
+// so the input isn't copied once more before it's passed to libexiv2
+func readCBytes(op, name string, r io.Reader) (unsafe.Pointer, int, error) {
+	capacity := 512
+	ptr := C.malloc(C.size_t(capacity))
+	if ptr == nil {
+		return nil, 0, newError(ErrorCodeMallocFailed, "cannot allocate the input buffer", op, name)
+	}
+	size := 0
+
+	for {
+		if size == capacity {
+			capacity *= 2
+			grown := C.realloc(ptr, C.size_t(capacity))
+			if grown == nil {
+				C.free(ptr)
+				return nil, 0, newError(ErrorCodeMallocFailed, "cannot allocate the input buffer", op, name)
+			}
+			ptr = grown
+		}
+
+		buf := unsafe.Slice((*byte)(ptr), capacity)
+		n, err := r.Read(buf[size:])
+		size += n
+
+		if err == io.EOF {
+			return ptr, size, nil
+		}
+		if err != nil {
+			C.free(ptr)
+			return nil, 0, err
+		}
+	}
+}
+
+// OpenReaderAt opens an image of the given size, which is read through r, and returns a pointer to
+// the corresponding Image object, but does not read the Metadata. Only the parts of the image
+// needed by exiv2 are read: for most formats ReadMetadata() reads just the metadata segments.
+// The whole image is read into memory when it is needed, i.e. to parse TIFF-based formats
+// (TIFF and most RAW formats), to write the metadata or for GetBytes().
+// r must remain readable until the image is closed.
+func OpenReaderAt(r io.ReaderAt, size int64) (*Image, error) {
+	return openReaderAt("OpenReaderAt", "", r, size, nil)
+}
+
+// OpenFS opens an image file from fsys, e.g. an embed.FS or a zip archive, and returns a pointer to
+// the corresponding Image object, but does not read the Metadata. If the file implements
+// io.ReaderAt, it is read on demand like with OpenReaderAt and stays open until the image is closed.
+func OpenFS(fsys fs.FS, name string) (*Image, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, fsError("OpenFS", name, err)
+	}
+
+	if r, ok := file.(io.ReaderAt); ok {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, fsError("OpenFS", name, err)
+		}
+
+		img, err := openReaderAt("OpenFS", name, r, info.Size(), file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		return img, nil
+	}
+	defer file.Close()
+
+	return openReader("OpenFS", name, file)
+}
+
+// fsError reports an error of a file system as op name, in the same format as an Error,
+// e.g. "OpenFS image.jpg: file does not exist". The error still matches fs.ErrNotExist and the like.
+func fsError(op, name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
 	}
 
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// openCBytes opens an image from C memory, which is owned by the returned Image
+// and freed along with it, or right away if the image can't be opened
+func openCBytes(op, name string, ptr unsafe.Pointer, size int) (*Image, error) {
 	var cerr *C.Exiv2Error
 
-	bytesArrayPtr := C.CBytes(input)
 	cimg := C.exiv2_image_factory_open_bytes(
-		(*C.uchar)(bytesArrayPtr),
-		C.long(len(input)),
+		(*C.uchar)(ptr),
+		C.long(size),
 		&cerr,
 	)
 
 	if cerr != nil {
-		err := makeError(cerr)
+		C.free(ptr)
+		err := makeError(cerr, op, name)
 		C.exiv2_error_free(cerr)
 		return nil, err
 	}
 
-	return makeImage(cimg, bytesArrayPtr), nil
+	return makeImage(cimg, ptr), nil
 }
 
 type LogMsgLevel int
@@ -125,23 +302,28 @@ const (
 	LogMsgMute              = 4
 )
 
-// SetLogMsgLevel Set the log level (outputs to stderr)
+// SetLogMsgLevel Set the log level (outputs to stderr unless a handler is set with SetLogHandler)
 func SetLogMsgLevel(level LogMsgLevel) {
 	C.exiv2_log_msg_set_level(C.int(level))
 }
 
 // ReadMetadata reads the metadata of an Image
 func (i *Image) ReadMetadata() error {
-	if i.img == nil {
-		return errors.New("image instance is not initialized: underlying C structure is nil")
+	if err := i.checkImage(); err != nil {
+		return err
 	}
 
+	var warnings []LogMessage
+	capture := cgo.NewHandle(&warnings)
+	defer capture.Delete()
+
 	var cerr *C.Exiv2Error
 
-	C.exiv2_image_read_metadata(i.img, &cerr)
+	C.exiv2_image_read_metadata(i.img, C.uintptr_t(capture), &cerr)
+	i.warnings = warnings
 
 	if cerr != nil {
-		err := makeError(cerr)
+		err := makeError(cerr, "ReadMetadata", "")
 		C.exiv2_error_free(cerr)
 		return err
 	}
@@ -157,7 +339,7 @@ func (i *Image) GetBytes() []byte {
 	}
 
 	size := C.exiv_image_get_size(i.img)
-	ptr := C.exiv_image_get_bytes_ptr(i.img)
+	ptr := C.exiv_image_get_bytes_ptr(i.img, nil)
 
 	if ptr == nil || size <= 0 {
 		return nil
@@ -170,6 +352,113 @@ func (i *Image) GetBytes() []byte {
 	return result
 }
 
+// WriteTo writes the image contents to w, along with the changes of its metadata.
+// The contents are passed to w directly from the memory of libexiv2, without a copy.
+func (i *Image) WriteTo(w io.Writer) (int64, error) {
+	if err := i.checkImage(); err != nil {
+		return 0, err
+	}
+
+	var cerr *C.Exiv2Error
+
+	size := C.exiv_image_get_size(i.img)
+	ptr := C.exiv_image_get_bytes_ptr(i.img, &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "WriteTo", "")
+		C.exiv2_error_free(cerr)
+		return 0, err
+	}
+
+	if ptr == nil || size <= 0 {
+		return 0, nil
+	}
+
+	n, err := w.Write(unsafe.Slice((*byte)(unsafe.Pointer(ptr)), int(size)))
+
+	runtime.KeepAlive(i) // The memory belongs to the C structure
+
+	if err == nil && n < int(size) {
+		err = io.ErrShortWrite
+	}
+
+	return int64(n), err
+}
+
+// SaveAs writes the image contents to a file atomically: the contents are written to a temporary
+// file in the same directory, which is synced to disk and then renamed to path. So the file at path
+// is either replaced completely or left as is, even if the process crashes. The permissions of
+// an existing file are kept.
+//
+// Note that an image opened with Open is rewritten in place by every metadata change, so saving it
+// under another path doesn't keep the original file intact. Open the image with OpenBytes or
+// OpenReader to leave the source untouched.
+func (i *Image) SaveAs(path string) (err error) {
+	if err := i.checkImage(); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = i.WriteTo(tmp); err != nil {
+		return err
+	}
+
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// sync the directory, so the rename itself survives a crash
+	return syncDir(dir)
+}
+
+// syncDir flushes a directory to disk. The platforms and filesystems which can't sync directories are ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTSUP) {
+		return err
+	}
+
+	return nil
+}
+
 // PixelWidth returns the width of the image in pixels
 func (i *Image) PixelWidth() int64 {
 	if i.img == nil {
@@ -194,6 +483,25 @@ func (i *Image) PixelHeight() int64 {
 	return result
 }
 
+// AccessMode returns how the image format supports a kind of metadata
+func (i *Image) AccessMode(f MetadataFormat) AccessMode {
+	id, ok := metadataIds[f]
+	if !ok || i.checkImage() != nil {
+		return AccessNone
+	}
+
+	result := AccessMode(C.exiv2_image_access_mode(i.img, id))
+	runtime.KeepAlive(i)
+
+	return result
+}
+
+// Supports returns true if the image format allows a kind of metadata to be accessed in the given mode,
+// e.g. Supports(goexiv.IPTC, goexiv.AccessWrite) is false for WebP images
+func (i *Image) Supports(f MetadataFormat, mode AccessMode) bool {
+	return mode != AccessNone && i.AccessMode(f)&mode == mode
+}
+
 // ICCProfile returns the ICC profile or nil if the image doesn't has one.
 func (i *Image) ICCProfile() []byte {
 	if i.img == nil {
@@ -211,14 +519,23 @@ func (i *Image) ICCProfile() []byte {
 	return result
 }
 
-// SetMetadataString sets an exif or iptc key with a given string value
+// SetMetadataString sets an exif, iptc or xmp key with a given string value.
+// Keys of another metadata format are rejected before anything is written.
 func (i *Image) SetMetadataString(format, key, value string) error {
-	if i.img == nil {
-		return errors.New("image instance is not initialized: underlying C structure is nil")
+	if err := i.checkImage(); err != nil {
+		return err
 	}
 
-	if format != "iptc" && format != "exif" {
-		return errors.New("invalid metadata type: " + format)
+	if format != "iptc" && format != "exif" && format != "xmp" {
+		return newError(ErrorCodeInvalidMetadataFormat, "invalid metadata type: "+format, "SetMetadataString", key)
+	}
+
+	if err := validateKey("SetMetadataString", format, key); err != nil {
+		return err
+	}
+
+	if format == "xmp" {
+		return i.SetXmpString(key, value)
 	}
 
 	cKey := C.CString(key)
@@ -238,7 +555,7 @@ func (i *Image) SetMetadataString(format, key, value string) error {
 	}
 
 	if cerr != nil {
-		err := makeError(cerr)
+		err := makeError(cerr, "SetMetadataString", key)
 		C.exiv2_error_free(cerr)
 		return err
 	}
@@ -246,14 +563,19 @@ func (i *Image) SetMetadataString(format, key, value string) error {
 	return nil
 }
 
-// SetMetadataShort sets an exif or iptc key with a given short value
+// SetMetadataShort sets an exif or iptc key with a given short value.
+// Keys of another metadata format are rejected before anything is written.
 func (i *Image) SetMetadataShort(format, key, value string) error {
-	if i.img == nil {
-		return errors.New("image instance is not initialized: underlying C structure is nil")
+	if err := i.checkImage(); err != nil {
+		return err
 	}
 
 	if format != "iptc" && format != "exif" {
-		return errors.New("invalid metadata type: " + format)
+		return newError(ErrorCodeInvalidMetadataFormat, "invalid metadata type: "+format, "SetMetadataShort", key)
+	}
+
+	if err := validateKey("SetMetadataShort", format, key); err != nil {
+		return err
 	}
 
 	cKey := C.CString(key)
@@ -273,7 +595,7 @@ func (i *Image) SetMetadataShort(format, key, value string) error {
 	}
 
 	if cerr != nil {
-		err := makeError(cerr)
+		err := makeError(cerr, "SetMetadataShort", key)
 		C.exiv2_error_free(cerr)
 		return err
 	}
@@ -281,9 +603,10 @@ func (i *Image) SetMetadataShort(format, key, value string) error {
 	return nil
 }
 
+// StripKey removes every occurrence of a key, e.g. all Iptc.Application2.Keywords datasets
 func (i *Image) StripKey(f MetadataFormat, key string) error {
-	if i.img == nil {
-		return errors.New("image instance is not initialized: underlying C structure is nil")
+	if err := i.checkImage(); err != nil {
+		return err
 	}
 
 	ckey := C.CString(key)
@@ -299,14 +622,225 @@ func (i *Image) StripKey(f MetadataFormat, key string) error {
 	case XMP:
 		C.exiv2_xmp_strip_key(i.img, ckey, &cErr)
 	default:
-		return errors.New("invalid metadata format")
+		return newError(ErrorCodeInvalidMetadataFormat, "invalid metadata format", "StripKey", key)
 	}
 
 	if cErr != nil {
-		err := makeError(cErr)
+		err := makeError(cErr, "StripKey", key)
 		C.exiv2_error_free(cErr)
 		return err
 	}
 
 	return nil
 }
+
+// StripGroup removes all keys of a group, e.g. Exif.GPSInfo or Xmp.xmpMM, with a single metadata write
+func (i *Image) StripGroup(f MetadataFormat, prefix string) error {
+	prefix = strings.TrimSuffix(prefix, ".") + "."
+
+	return i.StripMatching(f, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// StripMatching removes all keys for which match returns true, with a single metadata write
+func (i *Image) StripMatching(f MetadataFormat, match func(key string) bool) error {
+	keys, err := i.metadataKeys(f)
+	if err != nil {
+		return err
+	}
+
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.stripKeys(f, keys, match)
+	})
+}
+
+// ClearMetadataOptions selects the metadata kept by ClearMetadata
+type ClearMetadataOptions struct {
+	// KeepICCProfile keeps the ICC profile, so the colors are rendered the same way
+	KeepICCProfile bool
+	// KeepOrientation keeps Exif.Image.Orientation and Xmp.tiff.Orientation
+	KeepOrientation bool
+	// KeepCopyright keeps Exif.Image.Copyright, Iptc.Application2.Copyright and Xmp.dc.rights
+	KeepCopyright bool
+	// KeepKeys lists any other EXIF, IPTC or XMP keys to keep
+	KeepKeys []string
+}
+
+// ClearMetadata removes all EXIF, IPTC and XMP data, the comment and the ICC profile
+// of the image, except for the pieces selected in opts, with a single metadata write
+func (i *Image) ClearMetadata(opts ClearMetadataOptions) error {
+	if err := i.checkImage(); err != nil {
+		return err
+	}
+
+	keepKeys := append([]string{}, opts.KeepKeys...)
+	if opts.KeepOrientation {
+		keepKeys = append(keepKeys, "Exif.Image.Orientation", "Xmp.tiff.Orientation")
+	}
+	if opts.KeepCopyright {
+		keepKeys = append(keepKeys, "Exif.Image.Copyright", "Iptc.Application2.Copyright", "Xmp.dc.rights")
+	}
+
+	cKeepKeys := makeCStringArray(keepKeys)
+	defer freeCStringArray(cKeepKeys, len(keepKeys))
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_image_clear_metadata(i.img, cKeepKeys, C.int(len(keepKeys)), cBool(opts.KeepICCProfile), &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "ClearMetadata", "")
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+// StripAll removes all metadata of the image, including the comment and the ICC profile
+func (i *Image) StripAll() error {
+	return i.ClearMetadata(ClearMetadataOptions{})
+}
+
+// CopyMetadataOptions selects the metadata copied by CopyMetadata
+type CopyMetadataOptions struct {
+	EXIF       bool
+	IPTC       bool
+	XMP        bool
+	ICCProfile bool
+	Comment    bool
+}
+
+// CopyAllMetadata copies every kind of metadata supported by CopyMetadata
+var CopyAllMetadata = CopyMetadataOptions{
+	EXIF:       true,
+	IPTC:       true,
+	XMP:        true,
+	ICCProfile: true,
+	Comment:    true,
+}
+
+// CopyMetadata copies the metadata selected in opts from src to dst and writes dst metadata once.
+// The metadata of src must be read with ReadMetadata() beforehand. The selected kinds of metadata
+// replace the ones of dst; those the dst format can't hold (e.g. IPTC in WebP) are skipped.
+// The images may be of different formats, e.g. a JPEG and its transcoded WebP version.
+func CopyMetadata(dst, src *Image, opts CopyMetadataOptions) error {
+	return dst.edit(func(tx *MetadataTx) error {
+		return tx.CopyMetadata(src, opts)
+	})
+}
+
+// CopyMetadata stages the metadata selected in opts from src, see the CopyMetadata function.
+// The metadata of src must be read with ReadMetadata() beforehand. Changes staged before
+// are overwritten for the copied kinds of metadata, later ones are applied on top of the copy.
+func (t *MetadataTx) CopyMetadata(src *Image, opts CopyMetadataOptions) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	if err := src.checkImage(); err != nil {
+		return err
+	}
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_metadata_tx_copy_metadata(
+		t.tx,
+		src.img,
+		cBool(opts.EXIF),
+		cBool(opts.IPTC),
+		cBool(opts.XMP),
+		cBool(opts.ICCProfile),
+		cBool(opts.Comment),
+		&cerr,
+	)
+
+	// t keeps the destination image alive
+	runtime.KeepAlive(t)
+	runtime.KeepAlive(src)
+
+	if cerr != nil {
+		err := makeError(cerr, "CopyMetadata", "")
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+// metadataKeys returns the distinct keys of a metadata format
+func (i *Image) metadataKeys(f MetadataFormat) ([]string, error) {
+	if err := i.checkImage(); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	seen := map[string]bool{}
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	switch f {
+	case EXIF:
+		data := i.GetExifData()
+		defer data.Close()
+
+		iter := data.Iterator()
+		defer iter.Close()
+
+		for iter.HasNext() {
+			datum := iter.Next()
+			if datum == nil {
+				break
+			}
+			add(datum.Key())
+			datum.Close()
+		}
+	case IPTC:
+		data := i.GetIptcData()
+		defer data.Close()
+
+		iter := data.Iterator()
+		defer iter.Close()
+
+		for iter.HasNext() {
+			datum := iter.Next()
+			if datum == nil {
+				break
+			}
+			add(datum.Key())
+			datum.Close()
+		}
+	case XMP:
+		data := i.GetXmpData()
+		defer data.Close()
+
+		iter := data.Iterator()
+		defer iter.Close()
+
+		for iter.HasNext() {
+			datum := iter.Next()
+			if datum == nil {
+				break
+			}
+			add(datum.Key())
+			datum.Close()
+		}
+	default:
+		return nil, newError(ErrorCodeInvalidMetadataFormat, "invalid metadata format", "", "")
+	}
+
+	return keys, nil
+}
+
+func cBool(b bool) C.int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -1,13 +1,23 @@
 package goexiv_test
 
 import (
+	"bytes"
+	"errors"
 	"github.com/kolesa-team/goexiv"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"math"
+	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
+	"time"
 )
 
 func TestOpenImage(t *testing.T) {
@@ -61,20 +71,20 @@ func Test_OpenBytesFailures(t *testing.T) {
 		{
 			"no image",
 			[]byte("no image"),
-			"Failed to read input data",
+			"OpenBytes: Failed to read input data",
 			20,
 		},
 		{
 			"empty byte slice",
 			[]byte{},
-			"input is empty",
-			0,
+			"OpenBytes: input is empty",
+			int(goexiv.ErrorCodeEmptyInput),
 		},
 		{
 			"nil byte slice",
 			nil,
-			"input is empty",
-			0,
+			"OpenBytes: input is empty",
+			int(goexiv.ErrorCodeEmptyInput),
 		},
 	}
 	for _, tt := range tests {
@@ -184,7 +194,7 @@ func TestNoMetadata(t *testing.T) {
 }
 
 type MetadataTestCase struct {
-	Format                 string // exif or iptc
+	Format                 string // exif, iptc or xmp
 	Key                    string
 	Value                  string
 	ImageFilename          string
@@ -217,6 +227,14 @@ var metadataSetStringTestCases = []MetadataTestCase{
 		ImageFilename:          "testdata/pixel.jpg",
 		ExpectedErrorSubstring: "",
 	},
+	// valid xmp key, jpeg
+	{
+		Format:                 "xmp",
+		Key:                    "Xmp.photoshop.Headline",
+		Value:                  "Hello, world! Привет, мир!",
+		ImageFilename:          "testdata/pixel.jpg",
+		ExpectedErrorSubstring: "",
+	},
 	// invalid exif key, jpeg
 	{
 		Format:                 "exif",
@@ -268,9 +286,12 @@ func Test_SetMetadataStringFromFile(t *testing.T) {
 		err = img.ReadMetadata()
 		require.NoErrorf(t, err, "case #%d Cannot read image metadata", i)
 
-		if testcase.Format == "iptc" {
+		switch testcase.Format {
+		case "iptc":
 			data = img.GetIptcData()
-		} else {
+		case "xmp":
+			data = img.GetXmpData()
+		default:
 			data = img.GetExifData()
 		}
 
@@ -379,6 +400,324 @@ func Test_SetMetadataShortInt(t *testing.T) {
 	}
 }
 
+func Test_DatumTypedValues(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	require.NoError(t, img.SetMetadataShort("exif", "Exif.Photo.ExposureProgram", "2"))
+	require.NoError(t, img.SetMetadataShort("iptc", "Iptc.Envelope.ModelVersion", "4"))
+	require.NoError(t, img.SetExifString("Exif.Image.Make", "FakeMake"))
+	require.NoError(t, img.ReadMetadata())
+
+	exifDatum, err := img.GetExifData().FindKey("Exif.Photo.ExposureProgram")
+	require.NoError(t, err)
+	require.NotNil(t, exifDatum)
+
+	assert.Equal(t, goexiv.TypeUnsignedShort, exifDatum.TypeID())
+	assert.Equal(t, "Short", exifDatum.TypeName())
+	assert.Equal(t, int64(1), exifDatum.Count())
+	assert.Equal(t, int64(2), exifDatum.Size())
+	assert.Equal(t, []byte{2, 0}, exifDatum.Bytes())
+
+	intValue, err := exifDatum.Int64(0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), intValue)
+
+	floatValue, err := exifDatum.Float64(0)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, floatValue)
+
+	rationalValue, err := exifDatum.Rational(0)
+	require.NoError(t, err)
+	assert.Equal(t, goexiv.Rational{Num: 2, Den: 1}, rationalValue)
+
+	_, err = exifDatum.Int64(1)
+	assert.Equal(t, goexiv.ErrValueIndexOutOfRange, err)
+
+	_, err = exifDatum.Float64(-1)
+	assert.Equal(t, goexiv.ErrValueIndexOutOfRange, err)
+
+	exifDatum, err = img.GetExifData().FindKey("Exif.Image.Make")
+	require.NoError(t, err)
+	require.NotNil(t, exifDatum)
+	assert.Equal(t, goexiv.TypeAsciiString, exifDatum.TypeID())
+	assert.Equal(t, "Ascii", exifDatum.TypeName())
+
+	iptcDatum, err := img.GetIptcData().FindKey("Iptc.Envelope.ModelVersion")
+	require.NoError(t, err)
+	require.NotNil(t, iptcDatum)
+
+	assert.Equal(t, goexiv.TypeUnsignedShort, iptcDatum.TypeID())
+	intValue, err = iptcDatum.Int64(0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), intValue)
+}
+
+func Test_MetadataTx(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	require.NoError(t, img.SetIptcString("Iptc.Application2.Caption", "to be removed"))
+
+	tx, err := img.Edit()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	require.NoError(t, tx.SetExifString("Exif.Image.Make", "FakeMake"))
+	require.NoError(t, tx.SetExifShort("Exif.Photo.ExposureProgram", "2"))
+	require.NoError(t, tx.SetIptcString("Iptc.Application2.CountryName", "Lancre"))
+	require.NoError(t, tx.SetIptcShort("Iptc.Envelope.ModelVersion", "4"))
+	require.NoError(t, tx.StripKey(goexiv.IPTC, "Iptc.Application2.Caption"))
+
+	err = tx.SetExifString("Exif.Invalid.Key", "value")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid key")
+
+	for _, set := range []func() error{
+		func() error { return tx.SetXmpString("Exif.Image.Make", "value") },
+		func() error { return tx.SetExifShort("Iptc.Envelope.ModelVersion", "4") },
+		func() error { return tx.SetIptcString("Exif.Image.Make", "value") },
+	} {
+		err = set()
+		assert.True(t, errors.Is(err, goexiv.ErrInvalidKey), err)
+	}
+
+	var exivErr *goexiv.Error
+	if assert.True(t, errors.As(tx.SetXmpString("Iptc.Application2.Caption", "value"), &exivErr)) {
+		assert.Equal(t, "SetXmpString", exivErr.Op())
+		assert.Equal(t, "Iptc.Application2.Caption", exivErr.Key())
+	}
+
+	// nothing is applied before the commit
+	require.NoError(t, img.ReadMetadata())
+	_, err = img.GetExifData().GetString("Exif.Image.Make")
+	assert.Equal(t, goexiv.ErrMetadataKeyNotFound, err)
+
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, goexiv.ErrTxDone, tx.Commit())
+	assert.Equal(t, goexiv.ErrTxDone, tx.SetExifString("Exif.Image.Model", "FakeModel"))
+
+	require.NoError(t, img.ReadMetadata())
+	exifData := img.GetExifData()
+	for key, value := range map[string]string{
+		"Exif.Image.Make":            "FakeMake",
+		"Exif.Photo.ExposureProgram": "2",
+	} {
+		receivedValue, err := exifData.GetString(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, value, receivedValue, key)
+	}
+	assert.Equal(t, map[string]string{
+		"Iptc.Application2.CountryName": "Lancre",
+		"Iptc.Envelope.ModelVersion":    "4",
+	}, img.GetIptcData().AllTags())
+
+	// rolled back changes are discarded
+	tx, err = img.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetExifString("Exif.Image.Model", "FakeModel"))
+	tx.Rollback()
+	assert.Equal(t, goexiv.ErrTxDone, tx.Commit())
+
+	require.NoError(t, img.ReadMetadata())
+	_, err = img.GetExifData().GetString("Exif.Image.Model")
+	assert.Equal(t, goexiv.ErrMetadataKeyNotFound, err)
+}
+
+func Test_SetXmp(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	require.NoError(t, img.SetXmpString("Xmp.photoshop.City", "Ankh-Morpork"))
+	require.NoError(t, img.SetMetadataString("xmp", "Xmp.photoshop.Country", "Discworld"))
+	require.NoError(t, img.SetXmpBag("Xmp.dc.subject", []string{"cat", "dog"}))
+	require.NoError(t, img.SetXmpSeq("Xmp.dc.creator", []string{"Alice", "Bob"}))
+	require.NoError(t, img.SetXmpLangAlt("Xmp.dc.title", map[string]string{
+		"x-default": "Title",
+		"ru-RU":     "Заголовок",
+	}))
+
+	err = img.SetXmpString("Xmp.invalidPrefix.Key", "value")
+	require.Error(t, err)
+
+	// array items, struct fields and properties which aren't predefined in a namespace can be written as well
+	tx, err := img.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetXmpBag("Xmp.xmpBJ.JobRef", nil))
+	require.NoError(t, tx.SetXmpString("Xmp.xmpBJ.JobRef[1]/stJob:name", "Birthday party"))
+	require.NoError(t, tx.SetXmpString("Xmp.dc.custom", "Custom"))
+	require.NoError(t, tx.Commit())
+
+	require.NoError(t, img.ReadMetadata())
+	data := img.GetXmpData()
+
+	datum, err := data.FindKey("Xmp.photoshop.City")
+	require.NoError(t, err)
+	require.NotNil(t, datum)
+	assert.Equal(t, goexiv.TypeXmpText, datum.TypeID())
+	assert.Equal(t, "Ankh-Morpork", datum.String())
+
+	datum, err = data.FindKey("Xmp.photoshop.Country")
+	require.NoError(t, err)
+	require.NotNil(t, datum)
+	assert.Equal(t, "Discworld", datum.String())
+
+	datum, err = data.FindKey("Xmp.dc.subject")
+	require.NoError(t, err)
+	require.NotNil(t, datum)
+	assert.Equal(t, goexiv.TypeXmpBag, datum.TypeID())
+	assert.Equal(t, int64(2), datum.Count())
+	assert.Equal(t, "cat, dog", datum.String())
+
+	datum, err = data.FindKey("Xmp.dc.creator")
+	require.NoError(t, err)
+	require.NotNil(t, datum)
+	assert.Equal(t, goexiv.TypeXmpSeq, datum.TypeID())
+	assert.Equal(t, int64(2), datum.Count())
+
+	datum, err = data.FindKey("Xmp.dc.title")
+	require.NoError(t, err)
+	require.NotNil(t, datum)
+	assert.Equal(t, goexiv.TypeLangAlt, datum.TypeID())
+	assert.Equal(t, int64(2), datum.Count())
+	assert.Equal(t, "Title", datum.String())
+
+	for key, value := range map[string]string{
+		"Xmp.xmpBJ.JobRef[1]/stJob:name": "Birthday party",
+		"Xmp.dc.custom":                  "Custom",
+	} {
+		receivedValue, err := data.GetString(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, value, receivedValue, key)
+	}
+}
+
+func Test_Close(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+	require.NoError(t, img.SetExifString("Exif.Image.Make", "FakeMake"))
+	require.NoError(t, img.ReadMetadata())
+
+	data := img.GetExifData()
+	datum, err := data.FindKey("Exif.Image.Make")
+	require.NoError(t, err)
+	require.NotNil(t, datum)
+	iter := data.Iterator()
+	require.True(t, iter.HasNext())
+
+	// closing a datum doesn't affect the data it came from
+	require.NoError(t, datum.Close())
+	require.NoError(t, datum.Close())
+	assert.Equal(t, "", datum.String())
+	assert.Equal(t, "", datum.Key())
+	_, err = datum.Int64(0)
+	assert.Equal(t, goexiv.ErrClosed, err)
+
+	value, err := data.GetString("Exif.Image.Make")
+	require.NoError(t, err)
+	assert.Equal(t, "FakeMake", value)
+
+	tx, err := img.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetExifString("Exif.Image.Model", "FakeModel"))
+
+	require.NoError(t, img.Close())
+	require.NoError(t, img.Close())
+
+	assert.Nil(t, img.GetBytes())
+	assert.Equal(t, int64(0), img.PixelWidth())
+	assert.Equal(t, goexiv.ErrClosed, img.ReadMetadata())
+	assert.Equal(t, goexiv.ErrClosed, img.SetExifString("Exif.Image.Make", "FakeMake"))
+	assert.Equal(t, goexiv.ErrClosed, img.ExifStripKey("Exif.Image.Make"))
+	assert.Equal(t, goexiv.ErrClosed, tx.Commit())
+
+	_, err = img.Edit()
+	assert.Equal(t, goexiv.ErrClosed, err)
+
+	// everything obtained from a closed image is unusable
+	assert.False(t, iter.HasNext())
+	assert.Nil(t, iter.Next())
+	assert.Empty(t, data.AllTags())
+	_, err = data.GetString("Exif.Image.Make")
+	assert.Equal(t, goexiv.ErrClosed, err)
+
+	_, err = img.GetIptcData().FindKey("Iptc.Application2.Caption")
+	assert.Equal(t, goexiv.ErrClosed, err)
+	assert.Empty(t, img.GetXmpData().AllTags())
+
+	require.NoError(t, iter.Close())
+	require.NoError(t, data.Close())
+	require.NoError(t, data.Close())
+}
+
+func Test_IptcRepeatedDatasets(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	require.NoError(t, img.SetIptcString("Iptc.Application2.Caption", "caption"))
+	require.NoError(t, img.SetIptcStrings("Iptc.Application2.Keywords", []string{"cat", "dog"}))
+	require.NoError(t, img.AddIptcString("Iptc.Application2.Keywords", "bird"))
+
+	err = img.AddIptcString("Iptc.Application2.Caption", "another caption")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not repeatable")
+
+	require.NoError(t, img.ReadMetadata())
+	data := img.GetIptcData()
+
+	datums, err := data.FindAll("Iptc.Application2.Keywords")
+	require.NoError(t, err)
+	values := []string{}
+	for _, datum := range datums {
+		values = append(values, datum.String())
+	}
+	assert.Equal(t, []string{"cat", "dog", "bird"}, values)
+
+	datums, err = data.FindAll("Iptc.0x0002.0x0019")
+	require.NoError(t, err)
+	assert.Len(t, datums, 3)
+
+	datums, err = data.FindAll("Iptc.Application2.Byline")
+	require.NoError(t, err)
+	assert.Empty(t, datums)
+
+	_, err = data.FindAll("Iptc.Invalid.Key")
+	require.Error(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"Iptc.Application2.Caption":  {"caption"},
+		"Iptc.Application2.Keywords": {"cat", "dog", "bird"},
+	}, data.AllTagsMulti())
+
+	require.NoError(t, img.SetIptcStrings("Iptc.Application2.Keywords", []string{"fish"}))
+	require.NoError(t, img.ReadMetadata())
+	assert.Equal(t, map[string][]string{
+		"Iptc.Application2.Caption":  {"caption"},
+		"Iptc.Application2.Keywords": {"fish"},
+	}, img.GetIptcData().AllTagsMulti())
+
+	require.NoError(t, img.SetIptcStrings("Iptc.Application2.Keywords", nil))
+	require.NoError(t, img.ReadMetadata())
+	assert.Equal(t, map[string]string{
+		"Iptc.Application2.Caption": "caption",
+	}, img.GetIptcData().AllTags())
+}
+
 func Test_GetBytes(t *testing.T) {
 	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
 	require.NoError(t, err)
@@ -486,23 +825,905 @@ func TestIptcStripKey(t *testing.T) {
 }
 
 func TestXmpStripKey(t *testing.T) {
-	t.Skip("XMP SetXmpString and GetString is not implemented yet")
-	//img, err := goexiv.Open("testdata/pixel.jpg")
-	//require.NoError(t, err)
-	//
-	//err = img.SetXmpString("Xmp.dc.description", "123")
-	//require.NoError(t, err)
-	//
-	//err = img.XmpStripKey("Xmp.dc.description")
-	//require.NoError(t, err)
-	//
-	//err = img.ReadMetadata()
-	//require.NoError(t, err)
-	//
-	//data := img.GetXmpData()
-	//
-	//_, err = data.GetString("Xmp.dc.description")
-	//require.Error(t, err)
+	img, err := goexiv.Open("testdata/pixel.jpg")
+	require.NoError(t, err)
+
+	err = img.SetXmpString("Xmp.dc.description", "123")
+	require.NoError(t, err)
+
+	err = img.XmpStripKey("Xmp.dc.description")
+	require.NoError(t, err)
+
+	err = img.ReadMetadata()
+	require.NoError(t, err)
+
+	data := img.GetXmpData()
+
+	_, err = data.GetString("Xmp.dc.description")
+	require.Error(t, err)
+}
+
+func TestXmpAllTags(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	tx, err := img.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetXmpString("Xmp.photoshop.City", "Ankh-Morpork"))
+	require.NoError(t, tx.SetXmpBag("Xmp.dc.subject", []string{"cat", "dog"}))
+	require.NoError(t, tx.Commit())
+
+	require.NoError(t, img.ReadMetadata())
+	data := img.GetXmpData()
+
+	assert.Equal(t, map[string]string{
+		"Xmp.photoshop.City": "Ankh-Morpork",
+		"Xmp.dc.subject":     "cat, dog",
+	}, data.AllTags())
+
+	keys := []string{}
+	for i := data.Iterator(); i.HasNext(); {
+		keys = append(keys, i.Next().Key())
+	}
+	assert.ElementsMatch(t, []string{"Xmp.photoshop.City", "Xmp.dc.subject"}, keys)
+
+	var provider goexiv.MetadataProvider = data
+	value, err := provider.GetString("Xmp.photoshop.City")
+	require.NoError(t, err)
+	assert.Equal(t, "Ankh-Morpork", value)
+
+	_, err = provider.GetString("Xmp.photoshop.Country")
+	assert.Equal(t, goexiv.ErrMetadataKeyNotFound, err)
+}
+
+func TestStripKeyRemovesAllOccurrences(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	require.NoError(t, img.SetIptcStrings("Iptc.Application2.Keywords", []string{"cat", "dog"}))
+	require.NoError(t, img.IptcStripKey("Iptc.Application2.Keywords"))
+
+	require.NoError(t, img.ReadMetadata())
+	datums, err := img.GetIptcData().FindAll("Iptc.Application2.Keywords")
+	require.NoError(t, err)
+	assert.Empty(t, datums)
+}
+
+func TestStripGroup(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	tx, err := img.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetExifString("Exif.Image.Make", "FakeMake"))
+	require.NoError(t, tx.SetExifString("Exif.GPSInfo.GPSLatitudeRef", "N"))
+	require.NoError(t, tx.SetExifString("Exif.GPSInfo.GPSLongitudeRef", "E"))
+	require.NoError(t, tx.SetXmpString("Xmp.xmpMM.DocumentID", "id"))
+	require.NoError(t, tx.SetXmpString("Xmp.xmpMM.InstanceID", "id"))
+	require.NoError(t, tx.SetXmpString("Xmp.photoshop.City", "Ankh-Morpork"))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, img.ReadMetadata())
+
+	require.NoError(t, img.StripGroup(goexiv.EXIF, "Exif.GPSInfo"))
+	require.NoError(t, img.StripGroup(goexiv.XMP, "Xmp.xmpMM."))
+
+	require.NoError(t, img.ReadMetadata())
+	exifTags := img.GetExifData().AllTags()
+	assert.Contains(t, exifTags, "Exif.Image.Make")
+	assert.NotContains(t, exifTags, "Exif.GPSInfo.GPSLatitudeRef")
+	assert.NotContains(t, exifTags, "Exif.GPSInfo.GPSLongitudeRef")
+	assert.Equal(t, map[string]string{
+		"Xmp.photoshop.City": "Ankh-Morpork",
+	}, img.GetXmpData().AllTags())
+
+	require.NoError(t, img.StripMatching(goexiv.XMP, func(key string) bool {
+		return key == "Xmp.photoshop.City"
+	}))
+	require.NoError(t, img.ReadMetadata())
+	assert.Empty(t, img.GetXmpData().AllTags())
+}
+
+func TestClearMetadata(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	tx, err := img.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetExifString("Exif.Image.Make", "FakeMake"))
+	require.NoError(t, tx.SetExifString("Exif.Image.Copyright", "Unseen University"))
+	require.NoError(t, tx.SetExifShort("Exif.Image.Orientation", "6"))
+	require.NoError(t, tx.SetIptcString("Iptc.Application2.Caption", "caption"))
+	require.NoError(t, tx.SetIptcString("Iptc.Application2.Copyright", "Unseen University"))
+	require.NoError(t, tx.SetIptcString("Iptc.Application2.City", "Ankh-Morpork"))
+	require.NoError(t, tx.SetXmpString("Xmp.photoshop.City", "Ankh-Morpork"))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, img.ReadMetadata())
+
+	require.NoError(t, img.ClearMetadata(goexiv.ClearMetadataOptions{
+		KeepOrientation: true,
+		KeepCopyright:   true,
+		KeepKeys:        []string{"Iptc.Application2.City"},
+	}))
+	require.NoError(t, img.ReadMetadata())
+
+	assert.Equal(t, map[string]string{
+		"Exif.Image.Copyright":   "Unseen University",
+		"Exif.Image.Orientation": "6",
+	}, img.GetExifData().AllTags())
+	assert.Equal(t, map[string]string{
+		"Iptc.Application2.Copyright": "Unseen University",
+		"Iptc.Application2.City":      "Ankh-Morpork",
+	}, img.GetIptcData().AllTags())
+	assert.Empty(t, img.GetXmpData().AllTags())
+
+	require.NoError(t, img.StripAll())
+	require.NoError(t, img.ReadMetadata())
+	assert.Empty(t, img.GetExifData().AllTags())
+	assert.Empty(t, img.GetIptcData().AllTags())
+	assert.Empty(t, img.GetXmpData().AllTags())
+	assert.Nil(t, img.ICCProfile())
+}
+
+func TestCopyMetadata(t *testing.T) {
+	jpegBytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+	webpBytes, err := ioutil.ReadFile("testdata/pixel.webp")
+	require.NoError(t, err)
+
+	src, err := goexiv.OpenBytes(jpegBytes)
+	require.NoError(t, err)
+
+	tx, err := src.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetExifString("Exif.Image.Make", "FakeMake"))
+	require.NoError(t, tx.SetIptcString("Iptc.Application2.Caption", "caption"))
+	require.NoError(t, tx.SetXmpString("Xmp.photoshop.City", "Ankh-Morpork"))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, src.ReadMetadata())
+
+	// JPEG to WebP: IPTC can't be written to WebP and is skipped
+	dst, err := goexiv.OpenBytes(webpBytes)
+	require.NoError(t, err)
+	require.NoError(t, goexiv.CopyMetadata(dst, src, goexiv.CopyAllMetadata))
+
+	dst, err = goexiv.OpenBytes(dst.GetBytes())
+	require.NoError(t, err)
+	require.NoError(t, dst.ReadMetadata())
+
+	value, err := dst.GetExifData().GetString("Exif.Image.Make")
+	require.NoError(t, err)
+	assert.Equal(t, "FakeMake", value)
+	assert.Equal(t, map[string]string{
+		"Xmp.photoshop.City": "Ankh-Morpork",
+	}, dst.GetXmpData().AllTags())
+
+	// JPEG to JPEG, IPTC only
+	dst, err = goexiv.OpenBytes(jpegBytes)
+	require.NoError(t, err)
+	require.NoError(t, goexiv.CopyMetadata(dst, src, goexiv.CopyMetadataOptions{IPTC: true}))
+	require.NoError(t, dst.ReadMetadata())
+
+	assert.Empty(t, dst.GetExifData().AllTags())
+	assert.Empty(t, dst.GetXmpData().AllTags())
+	assert.Equal(t, map[string]string{
+		"Iptc.Application2.Caption": "caption",
+	}, dst.GetIptcData().AllTags())
+
+	// the copy can be combined with other changes in a transaction
+	dst, err = goexiv.OpenBytes(jpegBytes)
+	require.NoError(t, err)
+	tx, err = dst.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.CopyMetadata(src, goexiv.CopyAllMetadata))
+	require.NoError(t, tx.SetExifString("Exif.Image.Model", "FakeModel"))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, dst.ReadMetadata())
+
+	assert.Equal(t, map[string]string{
+		"Exif.Image.Make":  "FakeMake",
+		"Exif.Image.Model": "FakeModel",
+	}, dst.GetExifData().AllTags())
+	assert.Equal(t, map[string]string{
+		"Iptc.Application2.Caption": "caption",
+	}, dst.GetIptcData().AllTags())
+}
+
+func TestGPS(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+
+	_, err = img.GetExifData().GPS()
+	assert.Equal(t, goexiv.ErrMetadataKeyNotFound, err)
+
+	altitude := -12.5
+	direction := 90.5
+	speed := 10.0
+	timestamp := time.Date(2020, 5, 17, 10, 20, 30, 0, time.UTC)
+
+	require.NoError(t, img.SetGPS(goexiv.GPSInfo{
+		Latitude:  43.238949,
+		Longitude: -76.889709,
+		Altitude:  &altitude,
+		Timestamp: &timestamp,
+		Direction: &direction,
+		Speed:     &speed,
+	}))
+	require.NoError(t, img.ReadMetadata())
+
+	info, err := img.GetExifData().GPS()
+	require.NoError(t, err)
+	assert.InDelta(t, 43.238949, info.Latitude, 1e-6)
+	assert.InDelta(t, -76.889709, info.Longitude, 1e-6)
+	require.NotNil(t, info.Altitude)
+	assert.InDelta(t, -12.5, *info.Altitude, 1e-6)
+	require.NotNil(t, info.Timestamp)
+	assert.True(t, timestamp.Equal(*info.Timestamp))
+	require.NotNil(t, info.Direction)
+	assert.InDelta(t, 90.5, *info.Direction, 1e-6)
+	assert.Equal(t, "T", info.DirectionRef)
+	require.NotNil(t, info.Speed)
+	assert.InDelta(t, 10, *info.Speed, 1e-6)
+	assert.Equal(t, "K", info.SpeedRef)
+
+	ref, err := img.GetExifData().GetString("Exif.GPSInfo.GPSLongitudeRef")
+	require.NoError(t, err)
+	assert.Equal(t, "W", ref)
+
+	// the time is rounded to milliseconds without yielding 60 seconds
+	almostMidnight := time.Date(2020, 5, 17, 23, 59, 59, 999600000, time.UTC)
+	require.NoError(t, img.SetGPS(goexiv.GPSInfo{Timestamp: &almostMidnight}))
+	require.NoError(t, img.ReadMetadata())
+
+	value, err := img.GetExifData().GetString("Exif.GPSInfo.GPSTimeStamp")
+	require.NoError(t, err)
+	assert.Equal(t, "0/1 0/1 0/1000", value)
+
+	info, err = img.GetExifData().GPS()
+	require.NoError(t, err)
+	require.NotNil(t, info.Timestamp)
+	assert.True(t, time.Date(2020, 5, 18, 0, 0, 0, 0, time.UTC).Equal(*info.Timestamp))
+
+	nan, inf, negative := math.NaN(), math.Inf(1), -1.0
+	fullCircle, tooLarge := 360.0, 1e8
+	invalid := []goexiv.GPSInfo{
+		{Latitude: 91},
+		{Longitude: -181},
+		{Latitude: nan},
+		{Longitude: inf},
+		{Altitude: &nan},
+		{Altitude: &inf},
+		{Direction: &nan},
+		{Direction: &negative},
+		{Speed: &inf},
+		{Speed: &negative},
+		{Direction: &fullCircle},
+		{Altitude: &tooLarge},
+		{Speed: &tooLarge},
+	}
+	for _, info := range invalid {
+		assert.Equal(t, goexiv.ErrInvalidGPSInfo, img.SetGPS(info), "%+v", info)
+	}
+
+	require.NoError(t, img.StripGPS())
+	require.NoError(t, img.ReadMetadata())
+
+	_, err = img.GetExifData().GPS()
+	assert.Equal(t, goexiv.ErrMetadataKeyNotFound, err)
+}
+
+func TestCaptureTime(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+
+	_, ok := img.CaptureTime()
+	assert.False(t, ok)
+
+	captured := time.Date(2020, 5, 17, 10, 20, 30, 250000000, time.FixedZone("", 3*60*60))
+	require.NoError(t, img.SetCaptureTime(captured))
+	require.NoError(t, img.ReadMetadata())
+
+	value, ok := img.CaptureTime()
+	require.True(t, ok)
+	assert.True(t, captured.Equal(value))
+	_, offset := value.Zone()
+	assert.Equal(t, 3*60*60, offset)
+
+	for key, expected := range map[string]string{
+		"Exif.Photo.DateTimeOriginal":   "2020:05:17 10:20:30",
+		"Exif.Photo.OffsetTimeOriginal": "+03:00",
+		"Exif.Photo.SubSecTimeOriginal": "25",
+	} {
+		value, err := img.GetExifData().GetString(key)
+		require.NoError(t, err)
+		assert.Equal(t, expected, value, key)
+	}
+
+	date, err := img.GetIptcData().GetString("Iptc.Application2.DateCreated")
+	require.NoError(t, err)
+	assert.Equal(t, "2020-05-17", date)
+
+	xmpDate, err := img.GetXmpData().GetString("Xmp.photoshop.DateCreated")
+	require.NoError(t, err)
+	assert.Equal(t, "2020-05-17T10:20:30.25+03:00", xmpDate)
+
+	// the offset is taken from IPTC when EXIF has none
+	require.NoError(t, img.StripKey(goexiv.EXIF, "Exif.Photo.OffsetTimeOriginal"))
+	require.NoError(t, img.StripKey(goexiv.XMP, "Xmp.exif.DateTimeOriginal"))
+	require.NoError(t, img.StripKey(goexiv.XMP, "Xmp.photoshop.DateCreated"))
+	require.NoError(t, img.ReadMetadata())
+
+	value, ok = img.CaptureTime()
+	require.True(t, ok)
+	assert.True(t, captured.Equal(value))
+}
+
+func TestShiftDates(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+
+	captured := time.Date(2020, 12, 31, 23, 0, 0, 0, time.FixedZone("", 3*60*60))
+	require.NoError(t, img.SetCaptureTime(captured))
+	require.NoError(t, img.SetGPS(goexiv.GPSInfo{Latitude: 1, Longitude: 1, Timestamp: &captured}))
+
+	tx, err := img.Edit()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetExifString("Exif.Image.DateTime", "2020:12:31 23:30:00"))
+	require.NoError(t, tx.SetXmpString("Xmp.xmp.CreateDate", "2020-12-31T23:00"))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, img.ReadMetadata())
+
+	require.NoError(t, img.ShiftDates(90*time.Minute, goexiv.ShiftAllDates))
+	require.NoError(t, img.ReadMetadata())
+
+	value, ok := img.CaptureTime()
+	require.True(t, ok)
+	assert.True(t, captured.Add(90*time.Minute).Equal(value))
+
+	for key, expected := range map[string]string{
+		"Exif.Image.DateTime":         "2021:01:01 01:00:00",
+		"Exif.Photo.DateTimeOriginal": "2021:01:01 00:30:00",
+		"Exif.GPSInfo.GPSDateStamp":   "2020:12:31",
+	} {
+		value, err := img.GetExifData().GetString(key)
+		require.NoError(t, err)
+		assert.Equal(t, expected, value, key)
+	}
+
+	for key, expected := range map[string]string{
+		"Xmp.photoshop.DateCreated": "2021-01-01T00:30:00+03:00",
+		"Xmp.xmp.CreateDate":        "2021-01-01T00:30",
+	} {
+		value, err := img.GetXmpData().GetString(key)
+		require.NoError(t, err)
+		assert.Equal(t, expected, value, key)
+	}
+
+	date, err := img.GetIptcData().GetString("Iptc.Application2.DateCreated")
+	require.NoError(t, err)
+	assert.Equal(t, "2021-01-01", date)
+
+	gps, err := img.GetExifData().GPS()
+	require.NoError(t, err)
+	require.NotNil(t, gps.Timestamp)
+	assert.True(t, captured.Add(90*time.Minute).Equal(*gps.Timestamp))
+}
+
+func TestOrientation(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+
+	orientation, err := img.Orientation()
+	require.NoError(t, err)
+	assert.Equal(t, goexiv.OrientationNormal, orientation)
+
+	require.NoError(t, img.SetXmpString("Xmp.tiff.Orientation", "3"))
+	require.NoError(t, img.ReadMetadata())
+
+	orientation, err = img.Orientation()
+	require.NoError(t, err)
+	assert.Equal(t, goexiv.OrientationRotate180, orientation)
+
+	require.NoError(t, img.SetOrientation(goexiv.OrientationRotate90))
+	require.NoError(t, img.ReadMetadata())
+
+	orientation, err = img.Orientation()
+	require.NoError(t, err)
+	assert.Equal(t, goexiv.OrientationRotate90, orientation)
+	assert.True(t, orientation.SwapsDimensions())
+
+	value, err := img.GetXmpData().GetString("Xmp.tiff.Orientation")
+	require.NoError(t, err)
+	assert.Equal(t, "6", value)
+
+	assert.Equal(t, goexiv.ErrInvalidOrientation, img.SetOrientation(9))
+}
+
+func TestPreviews(t *testing.T) {
+	img, err := goexiv.Open("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+
+	previews, err := img.Previews()
+	require.NoError(t, err)
+	assert.Empty(t, previews)
+
+	_, err = img.PreviewBytes(0)
+	assert.Equal(t, goexiv.ErrPreviewNotFound, err)
+
+	_, err = img.PreviewBytes(-1)
+	assert.Equal(t, goexiv.ErrPreviewNotFound, err)
+}
+
+func TestExifThumbnail(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(bytes)
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+
+	thumbnail, mimeType, err := img.ExifThumbnail()
+	require.NoError(t, err)
+	assert.Nil(t, thumbnail)
+	assert.Equal(t, "", mimeType)
+
+	assert.Equal(t, goexiv.ErrInvalidThumbnail, img.SetExifThumbnail([]byte("not a jpeg")))
+
+	require.NoError(t, img.SetExifThumbnail(bytes))
+	require.NoError(t, img.ReadMetadata())
+
+	thumbnail, mimeType, err = img.ExifThumbnail()
+	require.NoError(t, err)
+	assert.Equal(t, bytes, thumbnail)
+	assert.Equal(t, "image/jpeg", mimeType)
+
+	// the thumbnail is one of the previews
+	previews, err := img.Previews()
+	require.NoError(t, err)
+	require.Len(t, previews, 1)
+	assert.Equal(t, "image/jpeg", previews[0].MimeType)
+	assert.Equal(t, int64(len(bytes)), previews[0].Size)
+
+	preview, err := img.PreviewBytes(0)
+	require.NoError(t, err)
+	assert.Equal(t, bytes, preview)
+
+	require.NoError(t, img.EraseExifThumbnail())
+	require.NoError(t, img.ReadMetadata())
+
+	thumbnail, _, err = img.ExifThumbnail()
+	require.NoError(t, err)
+	assert.Nil(t, thumbnail)
+
+	require.NoError(t, img.Close())
+	_, _, err = img.ExifThumbnail()
+	assert.Equal(t, goexiv.ErrClosed, err)
+}
+
+func TestFormat(t *testing.T) {
+	for path, expected := range map[string]struct {
+		format   goexiv.ImageFormat
+		mimeType string
+	}{
+		"testdata/pixel.jpg":  {goexiv.FormatJPEG, "image/jpeg"},
+		"testdata/pixel.webp": {goexiv.FormatWebP, "image/webp"},
+	} {
+		bytes, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		assert.Equal(t, expected.format, goexiv.DetectFormat(bytes), path)
+
+		img, err := goexiv.OpenBytes(bytes)
+		require.NoError(t, err)
+		assert.Equal(t, expected.format, img.Format(), path)
+		assert.Equal(t, expected.mimeType, img.MimeType(), path)
+	}
+
+	assert.Equal(t, goexiv.FormatUnknown, goexiv.DetectFormat([]byte("not an image")))
+	assert.Equal(t, goexiv.FormatUnknown, goexiv.DetectFormat(nil))
+	assert.Equal(t, "WebP", goexiv.FormatWebP.String())
+}
+
+func TestSupports(t *testing.T) {
+	jpegImg, err := goexiv.Open("testdata/pixel.jpg")
+	require.NoError(t, err)
+
+	for _, format := range []goexiv.MetadataFormat{goexiv.EXIF, goexiv.IPTC, goexiv.XMP, goexiv.COMMENT, goexiv.ICC} {
+		assert.True(t, jpegImg.Supports(format, goexiv.AccessReadWrite), format)
+	}
+
+	webpImg, err := goexiv.Open("testdata/pixel.webp")
+	require.NoError(t, err)
+
+	assert.True(t, webpImg.Supports(goexiv.EXIF, goexiv.AccessWrite))
+	assert.True(t, webpImg.Supports(goexiv.XMP, goexiv.AccessWrite))
+	assert.True(t, webpImg.Supports(goexiv.ICC, goexiv.AccessWrite))
+	assert.False(t, webpImg.Supports(goexiv.IPTC, goexiv.AccessWrite))
+	assert.False(t, webpImg.Supports(goexiv.COMMENT, goexiv.AccessWrite))
+	assert.Equal(t, goexiv.AccessNone, webpImg.AccessMode(goexiv.IPTC))
+
+	require.NoError(t, webpImg.Close())
+	assert.False(t, webpImg.Supports(goexiv.EXIF, goexiv.AccessRead))
+}
+
+func TestOpenReader(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/pixel.jpg")
+	require.NoError(t, err)
+
+	open := map[string]func() (*goexiv.Image, error){
+		"OpenReader": func() (*goexiv.Image, error) {
+			return goexiv.OpenReader(bytes.NewBuffer(data))
+		},
+		"OpenReader one byte at a time": func() (*goexiv.Image, error) {
+			return goexiv.OpenReader(iotest.OneByteReader(bytes.NewReader(data)))
+		},
+		"OpenReaderAt": func() (*goexiv.Image, error) {
+			return goexiv.OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+		},
+		"OpenFS": func() (*goexiv.Image, error) {
+			return goexiv.OpenFS(os.DirFS("testdata"), "pixel.jpg")
+		},
+		"OpenFS map": func() (*goexiv.Image, error) {
+			return goexiv.OpenFS(fstest.MapFS{"pixel.jpg": {Data: data}}, "pixel.jpg")
+		},
+	}
+
+	for name, fn := range open {
+		img, err := fn()
+		require.NoError(t, err, name)
+		require.NoError(t, img.ReadMetadata(), name)
+
+		value, err := img.GetExifData().GetString("Exif.Image.Make")
+		require.NoError(t, err, name)
+		assert.Equal(t, "FakeMake", value, name)
+		require.NoError(t, img.Close())
+	}
+
+	// the metadata of a lazily read image can be changed as well
+	img, err := goexiv.OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+	require.NoError(t, img.SetExifString("Exif.Image.Make", "ReaderMake"))
+
+	img, err = goexiv.OpenBytes(img.GetBytes())
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+
+	value, err := img.GetExifData().GetString("Exif.Image.Make")
+	require.NoError(t, err)
+	assert.Equal(t, "ReaderMake", value)
+
+	_, err = goexiv.OpenReaderAt(failingReaderAt{}, 100)
+	assert.Error(t, err)
+
+	_, err = goexiv.OpenReaderAt(strings.NewReader("no image"), 8)
+	assert.True(t, errors.Is(err, goexiv.ErrUnsupportedImageType))
+
+	_, err = goexiv.OpenReader(bytes.NewBuffer(nil))
+	assert.EqualError(t, err, "OpenReader: input is empty")
+
+	_, err = goexiv.OpenReader(iotest.ErrReader(io.ErrUnexpectedEOF))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = goexiv.OpenFS(os.DirFS("testdata"), "missing.jpg")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Regexp(t, "^OpenFS missing.jpg: ", err.Error())
+}
+
+func TestErrors(t *testing.T) {
+	_, err := goexiv.Open("thisimagedoesnotexist")
+	assert.True(t, errors.Is(err, goexiv.ErrDataSourceOpenFailed))
+	assert.True(t, errors.Is(err, goexiv.ErrorCodeDataSourceOpenFailed))
+	assert.False(t, errors.Is(err, goexiv.ErrInvalidKey))
+	assert.Regexp(t, "^Open thisimagedoesnotexist: ", err.Error())
+
+	var exivErr *goexiv.Error
+	if assert.True(t, errors.As(err, &exivErr)) {
+		assert.Equal(t, "Open", exivErr.Op())
+		assert.Equal(t, "thisimagedoesnotexist", exivErr.Key())
+	}
+
+	_, err = goexiv.OpenBytes(nil)
+	assert.True(t, errors.Is(err, goexiv.ErrEmptyInput))
+	assert.True(t, errors.Is(err, goexiv.ErrorCodeEmptyInput))
+
+	initializeImage("testdata/pixel.jpg", t)
+	img, err := goexiv.Open("testdata/pixel.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	err = img.SetMetadataString("exif", "Exif.Invalid", "value")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, goexiv.ErrInvalidKey))
+	assert.Regexp(t, "^SetMetadataString Exif.Invalid: ", err.Error())
+
+	if assert.True(t, errors.As(err, &exivErr)) {
+		assert.Equal(t, "SetMetadataString", exivErr.Op())
+		assert.Equal(t, "Exif.Invalid", exivErr.Key())
+	}
+
+	err = img.SetMetadataString("unknown", "Exif.Image.Make", "value")
+	assert.True(t, errors.Is(err, goexiv.ErrInvalidMetadataFormat))
+	assert.True(t, errors.Is(err, goexiv.ErrorCodeInvalidMetadataFormat))
+
+	var zero goexiv.Image
+	err = zero.ReadMetadata()
+	assert.True(t, errors.Is(err, goexiv.ErrNotInitialized))
+}
+
+func TestStdException(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/pixel.jpg")
+	require.NoError(t, err)
+
+	// the image claims to be far larger than the memory, so libexiv2 fails with std::bad_alloc
+	// when it loads the image for WriteTo
+	img, err := goexiv.OpenReaderAt(bytes.NewReader(data), 1<<60)
+	if errors.Is(err, goexiv.ErrorCodeInputTooLarge) {
+		t.Skip("C long is too small")
+	}
+	require.NoError(t, err)
+	defer img.Close()
+
+	_, err = img.WriteTo(io.Discard)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, goexiv.ErrInternal))
+	assert.True(t, errors.Is(err, goexiv.ErrorCodeStdException))
+	assert.Regexp(t, "^WriteTo: ", err.Error())
+}
+
+func TestLookupTag(t *testing.T) {
+	tag, err := goexiv.LookupTag("Exif.Photo.ExposureTime")
+	require.NoError(t, err)
+	assert.Equal(t, goexiv.TagInfo{
+		Key:         "Exif.Photo.ExposureTime",
+		ID:          0x829a,
+		Group:       "Photo",
+		Name:        "ExposureTime",
+		Label:       "Exposure Time",
+		Description: tag.Description,
+		Type:        goexiv.TypeUnsignedRational,
+		Count:       1,
+	}, tag)
+	assert.NotEmpty(t, tag.Description)
+
+	tag, err = goexiv.LookupTag("Iptc.Application2.Caption")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(120), tag.ID)
+	assert.Equal(t, "Application2", tag.Group)
+	assert.Equal(t, goexiv.TypeString, tag.Type)
+	assert.Equal(t, -1, tag.Count)
+
+	tag, err = goexiv.LookupTag("Xmp.dc.title")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0), tag.ID)
+	assert.Equal(t, "dc", tag.Group)
+	assert.Equal(t, "Title", tag.Label)
+	assert.Equal(t, goexiv.TypeLangAlt, tag.Type)
+
+	for _, key := range []string{"Exif.Photo.Bogus", "Iptc.Bogus.Caption", "Xmp.bogus.title", "Xmp.dc.bogus", "Bogus.Key", "nodots"} {
+		_, err = goexiv.LookupTag(key)
+		assert.True(t, errors.Is(err, goexiv.ErrInvalidKey), key)
+	}
+}
+
+func TestListTags(t *testing.T) {
+	for group, key := range map[string]string{
+		"Exif.GPSInfo":      "Exif.GPSInfo.GPSLatitude",
+		"Iptc.Envelope":     "Iptc.Envelope.ModelVersion",
+		"Iptc.Application2": "Iptc.Application2.Keywords",
+		"Xmp.dc":            "Xmp.dc.subject",
+	} {
+		tags, err := goexiv.ListTags(group)
+		require.NoError(t, err, group)
+
+		found := false
+		for _, tag := range tags {
+			assert.True(t, strings.HasPrefix(tag.Key, group+"."), tag.Key)
+			found = found || tag.Key == key
+		}
+		assert.True(t, found, key)
+	}
+
+	_, err := goexiv.ListTags("Exif.Bogus")
+	assert.Error(t, err)
+
+	_, err = goexiv.ListTags("Bogus")
+	assert.Error(t, err)
+}
+
+func TestSetMetadataStringValidatesKey(t *testing.T) {
+	initializeImage("testdata/pixel.jpg", t)
+	img, err := goexiv.Open("testdata/pixel.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	err = img.SetMetadataString("iptc", "Exif.Image.Make", "value")
+	assert.True(t, errors.Is(err, goexiv.ErrInvalidKey))
+
+	err = img.SetMetadataShort("exif", "Exif.Photo.Bogus", "1")
+	assert.True(t, errors.Is(err, goexiv.ErrInvalidKey))
+
+	require.NoError(t, img.ReadMetadata())
+	value, err := img.GetExifData().GetString("Exif.Image.Make")
+	require.NoError(t, err)
+	assert.Equal(t, "FakeMake", value)
+}
+
+func TestInterpreted(t *testing.T) {
+	initializeImage("testdata/pixel.jpg", t)
+	img, err := goexiv.Open("testdata/pixel.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.SetMetadataShort("exif", "Exif.Photo.ExposureProgram", "2"))
+	require.NoError(t, img.SetExifString("Exif.Photo.ExposureTime", "1/250"))
+	require.NoError(t, img.SetExifString("Exif.Photo.FNumber", "28/10"))
+	require.NoError(t, img.SetXmpString("Xmp.exif.ExposureProgram", "2"))
+	require.NoError(t, img.ReadMetadata())
+
+	exif := img.GetExifData()
+	datum, err := exif.FindKey("Exif.Photo.ExposureTime")
+	require.NoError(t, err)
+	require.NotNil(t, datum)
+	assert.Equal(t, "1/250", datum.String())
+	assert.Equal(t, "1/250 s", datum.Interpreted())
+
+	tags := exif.AllTagsInterpreted()
+	assert.Equal(t, "Normal program", tags["Exif.Photo.ExposureProgram"])
+	assert.Equal(t, "F2.8", tags["Exif.Photo.FNumber"])
+	assert.Equal(t, "inch", tags["Exif.Image.ResolutionUnit"])
+	assert.Equal(t, "FakeMake", tags["Exif.Image.Make"])
+	assert.Equal(t, len(exif.AllTags()), len(tags))
+
+	assert.Equal(t, "Normal program", img.GetXmpData().AllTagsInterpreted()["Xmp.exif.ExposureProgram"])
+	assert.Equal(t, "Lancre", img.GetIptcData().AllTagsInterpreted()["Iptc.Application2.CountryName"])
+
+	datum.Close()
+	assert.Equal(t, "", datum.Interpreted())
+}
+
+// corruptedTiff is a TIFF image with an Exif.Image.Make entry pointing outside of the file
+var corruptedTiff = []byte{
+	'I', 'I', 0x2a, 0x00, 0x08, 0x00, 0x00, 0x00,
+	0x01, 0x00,
+	0x0f, 0x01, 0x02, 0x00, 0x64, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00,
+}
+
+func TestLogHandler(t *testing.T) {
+	var messages []goexiv.LogMessage
+	goexiv.SetLogHandler(func(level goexiv.LogMsgLevel, msg string) {
+		messages = append(messages, goexiv.LogMessage{Level: level, Message: msg})
+	})
+	defer goexiv.SetLogHandler(nil)
+
+	img, err := goexiv.OpenBytes(corruptedTiff)
+	require.NoError(t, err)
+	defer img.Close()
+
+	assert.Empty(t, img.Warnings())
+	messages = nil
+	require.NoError(t, img.ReadMetadata())
+
+	warnings := img.Warnings()
+	require.NotEmpty(t, warnings)
+	assert.Equal(t, warnings, messages)
+	for _, w := range warnings {
+		assert.True(t, w.Level >= goexiv.LogMsgWarn)
+		assert.NotContains(t, w.Message, "\n")
+	}
+
+	// a valid image is read without warnings
+	initializeImage("testdata/pixel.jpg", t)
+	valid, err := goexiv.Open("testdata/pixel.jpg")
+	require.NoError(t, err)
+	defer valid.Close()
+
+	require.NoError(t, valid.ReadMetadata())
+	assert.Empty(t, valid.Warnings())
+
+	// the warnings are captured without a handler too
+	goexiv.SetLogHandler(nil)
+	messages = nil
+	require.NoError(t, img.ReadMetadata())
+	assert.Equal(t, warnings, img.Warnings())
+	assert.Empty(t, messages)
+}
+
+type failingReaderAt struct{}
+
+func (failingReaderAt) ReadAt([]byte, int64) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestWriteTo(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	img, err := goexiv.OpenBytes(data)
+	require.NoError(t, err)
+	require.NoError(t, img.SetExifString("Exif.Image.Make", "FakeMake"))
+
+	var buf bytes.Buffer
+	n, err := img.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, img.GetBytes(), buf.Bytes())
+
+	n, err = img.WriteTo(shortWriter{})
+	assert.Equal(t, io.ErrShortWrite, err)
+	assert.Equal(t, int64(1), n)
+}
+
+// shortWriter accepts only the first byte of every write without an error
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) {
+	return 1, nil
+}
+
+func TestSaveAs(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/stripped_pixel.jpg")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	path := dir + "/image.jpg"
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+
+	img, err := goexiv.Open(path)
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+
+	// the changes are made in memory and then saved to another file
+	saved, err := goexiv.OpenBytes(data)
+	require.NoError(t, err)
+	require.NoError(t, saved.SetExifString("Exif.Image.Make", "FakeMake"))
+	require.NoError(t, saved.SaveAs(path))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	img, err = goexiv.Open(path)
+	require.NoError(t, err)
+	require.NoError(t, img.ReadMetadata())
+
+	value, err := img.GetExifData().GetString("Exif.Image.Make")
+	require.NoError(t, err)
+	assert.Equal(t, "FakeMake", value)
+
+	assert.Error(t, saved.SaveAs(dir+"/missing/image.jpg"))
 }
 
 func BenchmarkImage_GetBytes_KeepAlive(b *testing.B) {
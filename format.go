@@ -0,0 +1,115 @@
+package goexiv
+
+// #cgo pkg-config: exiv2
+// #include "helper.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// ImageFormat is the file format of an image as recognized by exiv2
+type ImageFormat int
+
+const (
+	FormatUnknown ImageFormat = C.EXIV2_FORMAT_UNKNOWN
+	FormatJPEG    ImageFormat = C.EXIV2_FORMAT_JPEG
+	// FormatEXV is the exiv2 metadata sidecar format
+	FormatEXV ImageFormat = C.EXIV2_FORMAT_EXV
+	// FormatCR2 is the Canon RAW format
+	FormatCR2 ImageFormat = C.EXIV2_FORMAT_CR2
+	// FormatCRW is the Canon Camera RAW format
+	FormatCRW  ImageFormat = C.EXIV2_FORMAT_CRW
+	FormatTIFF ImageFormat = C.EXIV2_FORMAT_TIFF
+	// FormatMRW is the Minolta RAW format
+	FormatMRW ImageFormat = C.EXIV2_FORMAT_MRW
+	// FormatORF is the Olympus RAW format
+	FormatORF ImageFormat = C.EXIV2_FORMAT_ORF
+	// FormatRW2 is the Panasonic RAW format
+	FormatRW2 ImageFormat = C.EXIV2_FORMAT_RW2
+	// FormatRAF is the Fujifilm RAW format
+	FormatRAF  ImageFormat = C.EXIV2_FORMAT_RAF
+	FormatPNG  ImageFormat = C.EXIV2_FORMAT_PNG
+	FormatGIF  ImageFormat = C.EXIV2_FORMAT_GIF
+	FormatPSD  ImageFormat = C.EXIV2_FORMAT_PSD
+	FormatTGA  ImageFormat = C.EXIV2_FORMAT_TGA
+	FormatBMP  ImageFormat = C.EXIV2_FORMAT_BMP
+	FormatJP2  ImageFormat = C.EXIV2_FORMAT_JP2
+	FormatPGF  ImageFormat = C.EXIV2_FORMAT_PGF
+	FormatWebP ImageFormat = C.EXIV2_FORMAT_WEBP
+	// FormatXMP is an XMP sidecar file
+	FormatXMP ImageFormat = C.EXIV2_FORMAT_XMP
+	FormatEPS ImageFormat = C.EXIV2_FORMAT_EPS
+	// FormatHEIF covers the ISO base media file formats: HEIF, AVIF and CR3.
+	// It is only detected by libexiv2 v0.27.4+ built with BMFF support.
+	FormatHEIF ImageFormat = C.EXIV2_FORMAT_HEIF
+)
+
+var imageFormatNames = map[ImageFormat]string{
+	FormatUnknown: "unknown",
+	FormatJPEG:    "JPEG",
+	FormatEXV:     "EXV",
+	FormatCR2:     "CR2",
+	FormatCRW:     "CRW",
+	FormatTIFF:    "TIFF",
+	FormatMRW:     "MRW",
+	FormatORF:     "ORF",
+	FormatRW2:     "RW2",
+	FormatRAF:     "RAF",
+	FormatPNG:     "PNG",
+	FormatGIF:     "GIF",
+	FormatPSD:     "PSD",
+	FormatTGA:     "TGA",
+	FormatBMP:     "BMP",
+	FormatJP2:     "JPEG 2000",
+	FormatPGF:     "PGF",
+	FormatWebP:    "WebP",
+	FormatXMP:     "XMP",
+	FormatEPS:     "EPS",
+	FormatHEIF:    "HEIF",
+}
+
+func (f ImageFormat) String() string {
+	if name, ok := imageFormatNames[f]; ok {
+		return name
+	}
+
+	return imageFormatNames[FormatUnknown]
+}
+
+// DetectFormat recognizes the format of an image by its first bytes, without parsing the whole image.
+// It returns FormatUnknown if the format isn't supported by exiv2.
+func DetectFormat(input []byte) ImageFormat {
+	if len(input) == 0 {
+		return FormatUnknown
+	}
+
+	return ImageFormat(C.exiv2_detect_format((*C.uchar)(unsafe.Pointer(&input[0])), C.long(len(input))))
+}
+
+// Format returns the format of the image
+func (i *Image) Format() ImageFormat {
+	if i.checkImage() != nil {
+		return FormatUnknown
+	}
+
+	result := ImageFormat(C.exiv2_image_format(i.img))
+	runtime.KeepAlive(i)
+
+	return result
+}
+
+// MimeType returns the MIME type of the image, e.g. "image/jpeg"
+func (i *Image) MimeType() string {
+	if i.checkImage() != nil {
+		return ""
+	}
+
+	cstr := C.exiv2_image_mime_type(i.img)
+	runtime.KeepAlive(i)
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
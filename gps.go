@@ -0,0 +1,322 @@
+package goexiv
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// GPSInfo holds the location an image was taken at, as stored in the Exif.GPSInfo group.
+type GPSInfo struct {
+	// Latitude in decimal degrees, negative for the southern hemisphere
+	Latitude float64
+	// Longitude in decimal degrees, negative for the western hemisphere
+	Longitude float64
+	// Altitude in meters, negative below the sea level
+	Altitude *float64
+	// Timestamp of the GPS fix in UTC
+	Timestamp *time.Time
+	// Direction of the image in degrees
+	Direction *float64
+	// DirectionRef is "T" for true north (the default on write) or "M" for magnetic north
+	DirectionRef string
+	// Speed of the GPS receiver
+	Speed *float64
+	// SpeedRef is "K" for km/h (the default on write), "M" for mph or "N" for knots
+	SpeedRef string
+}
+
+var ErrInvalidGPSInfo = errors.New("invalid GPS info")
+
+// denominators of the rationals the GPS values are written with
+const (
+	gpsAltitudeDenominator  = 1000
+	gpsDirectionDenominator = 100
+	gpsSpeedDenominator     = 100
+)
+
+// GPS returns the location the image was taken at.
+// It returns ErrMetadataKeyNotFound if the image has no GPS coordinates.
+func (d *ExifData) GPS() (GPSInfo, error) {
+	var info GPSInfo
+
+	latitude, err := d.gpsCoordinate("Exif.GPSInfo.GPSLatitude", "S")
+	if err != nil {
+		return info, err
+	}
+
+	longitude, err := d.gpsCoordinate("Exif.GPSInfo.GPSLongitude", "W")
+	if err != nil {
+		return info, err
+	}
+
+	info.Latitude = latitude
+	info.Longitude = longitude
+
+	altitude, err := d.gpsFloats("Exif.GPSInfo.GPSAltitude")
+	if err != nil {
+		return info, err
+	}
+	if len(altitude) > 0 {
+		value := altitude[0]
+		if ref, _ := d.gpsFloats("Exif.GPSInfo.GPSAltitudeRef"); len(ref) > 0 && ref[0] == 1 {
+			value = -value
+		}
+		info.Altitude = &value
+	}
+
+	timestamp, err := d.gpsTimestamp()
+	if err != nil {
+		return info, err
+	}
+	info.Timestamp = timestamp
+
+	direction, err := d.gpsFloats("Exif.GPSInfo.GPSImgDirection")
+	if err != nil {
+		return info, err
+	}
+	if len(direction) > 0 {
+		info.Direction = &direction[0]
+		info.DirectionRef, _ = d.GetString("Exif.GPSInfo.GPSImgDirectionRef")
+	}
+
+	speed, err := d.gpsFloats("Exif.GPSInfo.GPSSpeed")
+	if err != nil {
+		return info, err
+	}
+	if len(speed) > 0 {
+		info.Speed = &speed[0]
+		info.SpeedRef, _ = d.GetString("Exif.GPSInfo.GPSSpeedRef")
+	}
+
+	return info, nil
+}
+
+// gpsFloats returns all components of a numeric GPS tag or nil if the tag doesn't exist
+func (d *ExifData) gpsFloats(key string) ([]float64, error) {
+	datum, err := d.FindKey(key)
+	if err != nil || datum == nil {
+		return nil, err
+	}
+	defer datum.Close()
+
+	values := make([]float64, datum.Count())
+	for n := range values {
+		if values[n], err = datum.Float64(n); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidGPSInfo, key, err)
+		}
+	}
+
+	return values, nil
+}
+
+// gpsCoordinate converts degrees, minutes and seconds to signed decimal degrees
+func (d *ExifData) gpsCoordinate(key, negativeRef string) (float64, error) {
+	dms, err := d.gpsFloats(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if dms == nil {
+		return 0, ErrMetadataKeyNotFound
+	}
+
+	var value float64
+	for n, divisor := range []float64{1, 60, 3600} {
+		if n < len(dms) {
+			value += dms[n] / divisor
+		}
+	}
+
+	if ref, _ := d.GetString(key + "Ref"); strings.EqualFold(strings.TrimSpace(ref), negativeRef) {
+		value = -value
+	}
+
+	return value, nil
+}
+
+// gpsTimestamp combines GPSDateStamp and GPSTimeStamp. It returns nil if any of them is missing.
+func (d *ExifData) gpsTimestamp() (*time.Time, error) {
+	date, err := d.GetString("Exif.GPSInfo.GPSDateStamp")
+	if err == ErrMetadataKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	hms, err := d.gpsFloats("Exif.GPSInfo.GPSTimeStamp")
+	if err != nil || len(hms) != 3 {
+		return nil, err
+	}
+
+	day, err := time.Parse("2006:01:02", strings.TrimSpace(date))
+	if err != nil {
+		return nil, fmt.Errorf("%w: Exif.GPSInfo.GPSDateStamp: %v", ErrInvalidGPSInfo, err)
+	}
+
+	timestamp := day.Add(
+		time.Duration(hms[0]*float64(time.Hour)) +
+			time.Duration(hms[1]*float64(time.Minute)) +
+			time.Duration(hms[2]*float64(time.Second)),
+	)
+
+	return &timestamp, nil
+}
+
+// SetGPS replaces the Exif.GPSInfo group of the image with the given location, with a single metadata write
+func (i *Image) SetGPS(info GPSInfo) error {
+	if !info.valid() {
+		return ErrInvalidGPSInfo
+	}
+
+	keys, err := i.metadataKeys(EXIF)
+	if err != nil {
+		return err
+	}
+
+	return i.edit(func(tx *MetadataTx) error {
+		if err := tx.stripKeys(EXIF, keys, isExifGPSKey); err != nil {
+			return err
+		}
+
+		tags := []typedValue{
+			{TypeUnsignedByte, "Exif.GPSInfo.GPSVersionID", "2 2 0 0"},
+			{TypeAsciiString, "Exif.GPSInfo.GPSLatitudeRef", gpsRef(info.Latitude, "N", "S")},
+			{TypeUnsignedRational, "Exif.GPSInfo.GPSLatitude", formatGPSCoordinate(info.Latitude)},
+			{TypeAsciiString, "Exif.GPSInfo.GPSLongitudeRef", gpsRef(info.Longitude, "E", "W")},
+			{TypeUnsignedRational, "Exif.GPSInfo.GPSLongitude", formatGPSCoordinate(info.Longitude)},
+		}
+
+		if info.Altitude != nil {
+			tags = append(tags,
+				typedValue{TypeUnsignedByte, "Exif.GPSInfo.GPSAltitudeRef", gpsRef(*info.Altitude, "0", "1")},
+				typedValue{TypeUnsignedRational, "Exif.GPSInfo.GPSAltitude", formatRational(math.Abs(*info.Altitude), gpsAltitudeDenominator)},
+			)
+		}
+
+		if info.Timestamp != nil {
+			tags = append(tags, gpsTimestampValues(*info.Timestamp)...)
+		}
+
+		if info.Direction != nil {
+			tags = append(tags,
+				typedValue{TypeAsciiString, "Exif.GPSInfo.GPSImgDirectionRef", defaultString(info.DirectionRef, "T")},
+				typedValue{TypeUnsignedRational, "Exif.GPSInfo.GPSImgDirection", formatRational(*info.Direction, gpsDirectionDenominator)},
+			)
+		}
+
+		if info.Speed != nil {
+			tags = append(tags,
+				typedValue{TypeAsciiString, "Exif.GPSInfo.GPSSpeedRef", defaultString(info.SpeedRef, "K")},
+				typedValue{TypeUnsignedRational, "Exif.GPSInfo.GPSSpeed", formatRational(*info.Speed, gpsSpeedDenominator)},
+			)
+		}
+
+		return tx.setValues("SetGPS", EXIF, tags)
+	})
+}
+
+// StripGPS removes the Exif.GPSInfo group and the XMP GPS properties of the image, with a single metadata write
+func (i *Image) StripGPS() error {
+	exifKeys, err := i.metadataKeys(EXIF)
+	if err != nil {
+		return err
+	}
+
+	xmpKeys, err := i.metadataKeys(XMP)
+	if err != nil {
+		return err
+	}
+
+	return i.edit(func(tx *MetadataTx) error {
+		if err := tx.stripKeys(EXIF, exifKeys, isExifGPSKey); err != nil {
+			return err
+		}
+
+		return tx.stripKeys(XMP, xmpKeys, func(key string) bool {
+			return strings.HasPrefix(key, "Xmp.exif.GPS")
+		})
+	})
+}
+
+// gpsTimestampValues splits a time to GPSDateStamp and GPSTimeStamp, both of which are in UTC
+func gpsTimestampValues(t time.Time) []typedValue {
+	// round to milliseconds before splitting, so that the seconds never reach 60
+	utc := t.UTC().Round(time.Millisecond)
+	milliseconds := utc.Second()*1000 + utc.Nanosecond()/int(time.Millisecond)
+
+	return []typedValue{
+		{TypeAsciiString, "Exif.GPSInfo.GPSDateStamp", utc.Format("2006:01:02")},
+		{
+			TypeUnsignedRational,
+			"Exif.GPSInfo.GPSTimeStamp",
+			fmt.Sprintf("%d/1 %d/1 %d/1000", utc.Hour(), utc.Minute(), milliseconds),
+		},
+	}
+}
+
+func isExifGPSKey(key string) bool {
+	return strings.HasPrefix(key, "Exif.GPSInfo.")
+}
+
+func gpsRef(value float64, positive, negative string) string {
+	if value < 0 {
+		return negative
+	}
+
+	return positive
+}
+
+// formatGPSCoordinate converts decimal degrees to degrees, minutes and seconds rationals
+func formatGPSCoordinate(value float64) string {
+	// count in 1/10000 of an arc second, so that rounding never yields 60 seconds
+	total := int64(math.Round(math.Abs(value) * 3600 * 10000))
+
+	return fmt.Sprintf("%d/1 %d/1 %d/10000", total/(3600*10000), total/(60*10000)%60, total%(60*10000))
+}
+
+func (info GPSInfo) valid() bool {
+	if !isFinite(info.Latitude) || math.Abs(info.Latitude) > 90 {
+		return false
+	}
+	if !isFinite(info.Longitude) || math.Abs(info.Longitude) > 180 {
+		return false
+	}
+
+	// altitude, direction and speed are stored as unsigned rationals
+	if info.Altitude != nil && !fitsRational(math.Abs(*info.Altitude), gpsAltitudeDenominator) {
+		return false
+	}
+	if info.Direction != nil && (!fitsRational(*info.Direction, gpsDirectionDenominator) || *info.Direction >= 360) {
+		return false
+	}
+	if info.Speed != nil && !fitsRational(*info.Speed, gpsSpeedDenominator) {
+		return false
+	}
+
+	return true
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// fitsRational returns true if the value can be written by formatRational as an unsigned rational,
+// whose numerator is 32-bit. NaN and infinite values never fit.
+func fitsRational(value float64, denominator int64) bool {
+	return value >= 0 && math.Round(value*float64(denominator)) <= math.MaxUint32
+}
+
+func formatRational(value float64, denominator int64) string {
+	return fmt.Sprintf("%d/%d", int64(math.Round(value*float64(denominator))), denominator)
+}
+
+func defaultString(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
@@ -2,8 +2,16 @@
 
 #include <exiv2/image.hpp>
 #include <exiv2/error.hpp>
+#include <exiv2/preview.hpp>
+#include <exiv2/exif.hpp>
+#include <exiv2/exiv2.hpp>
 
 #include <stdio.h>
+#include <atomic>
+#include <exception>
+#include <set>
+#include <string>
+#include <vector>
 
 #define DEFINE_STRUCT(name,wrapped_type,member_name) \
 struct _##name { \
@@ -23,6 +31,14 @@ DEFINE_STRUCT(Exiv2Image, Exiv2::Image::AutoPtr, image);
 
 DEFINE_STRUCT(Exiv2XmpData, const Exiv2::XmpData&, data);
 DEFINE_STRUCT(Exiv2XmpDatum, const Exiv2::Xmpdatum&, datum);
+struct _Exiv2XmpDatumIterator {
+	_Exiv2XmpDatumIterator(Exiv2::XmpData::const_iterator i, Exiv2::XmpData::const_iterator e) : it(i), end(e) {}
+	Exiv2::XmpData::const_iterator it;
+	Exiv2::XmpData::const_iterator end;
+
+	bool has_next() const;
+	Exiv2XmpDatum* next();
+};
 
 DEFINE_STRUCT(Exiv2ExifData, const Exiv2::ExifData&, data);
 DEFINE_STRUCT(Exiv2ExifDatum, const Exiv2::Exifdatum&, datum);
@@ -46,11 +62,15 @@ struct _Exiv2IptcDatumIterator {
 	Exiv2IptcDatum* next();
 };
 
+DEFINE_STRUCT(Exiv2PreviewList, Exiv2::PreviewPropertiesList, list);
+
+DEFINE_FREE_FUNCTION(exiv2_xmp_datum_iterator, Exiv2XmpDatumIterator*);
 DEFINE_FREE_FUNCTION(exiv2_iptc_datum_iterator, Exiv2IptcDatumIterator*);
 DEFINE_FREE_FUNCTION(exiv2_exif_datum_iterator, Exiv2ExifDatumIterator*);
 
 struct _Exiv2Error {
 	_Exiv2Error(const Exiv2::Error &error);
+	_Exiv2Error(int code, const char *what);
 
 	int code;
 	char *what;
@@ -62,6 +82,35 @@ _Exiv2Error::_Exiv2Error(const Exiv2::Error &error)
 {
 }
 
+_Exiv2Error::_Exiv2Error(int code, const char *what)
+	: code(code)
+	, what(strdup(what))
+{
+}
+
+// set_error converts the exception being handled into an Exiv2Error.
+// No exception may unwind through cgo, so every entry point catches (...) and calls set_error().
+// The entry points without an error parameter return a neutral value instead: 0, NULL or EXIV2_FORMAT_UNKNOWN.
+static void
+set_error(Exiv2Error **error)
+{
+	try {
+		throw;
+	} catch (Exiv2::Error &e) {
+		if (error) {
+			*error = new Exiv2Error(e);
+		}
+	} catch (std::exception &e) {
+		if (error) {
+			*error = new Exiv2Error(GOEXIV_ERROR_STD_EXCEPTION, e.what());
+		}
+	} catch (...) {
+		if (error) {
+			*error = new Exiv2Error(GOEXIV_ERROR_UNKNOWN_EXCEPTION, "unknown exception");
+		}
+	}
+}
+
 Exiv2Image*
 exiv2_image_factory_open(const char *path, Exiv2Error **error)
 {
@@ -70,12 +119,10 @@ exiv2_image_factory_open(const char *path, Exiv2Error **error)
 	try {
 		p = new Exiv2Image(Exiv2::ImageFactory::open(path));
 		return p;
-	} catch (Exiv2::Error &e) {
+	} catch (...) {
 		delete p;
 
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
+		set_error(error);
 	}
 
 	return 0;
@@ -89,393 +136,1796 @@ exiv2_image_factory_open_bytes(const unsigned char *bytes, long size, Exiv2Error
 	try {
 		p = new Exiv2Image(Exiv2::ImageFactory::open(bytes, size));
 		return p;
-	} catch (Exiv2::Error &e) {
+	} catch (...) {
 		delete p;
 
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
+		set_error(error);
 	}
 
 	return 0;
 }
 
-void
-exiv2_image_read_metadata(Exiv2Image *img, Exiv2Error **error)
-{
-	try {
-		img->image->readMetadata();
-	} catch (Exiv2::Error &e) {
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
-	}
-}
+// GoReaderIo reads an image through goexiv_reader_read_at(), which calls back into an io.ReaderAt.
+// Only the parts of the image parsed by exiv2 are read. Once the whole image is needed
+// (for writing or for mmap(), which TIFF-based formats use to parse the metadata),
+// it is loaded into a MemIo, which serves all subsequent calls.
+class GoReaderIo : public Exiv2::BasicIo {
+public:
+	GoReaderIo(uintptr_t handle, long size)
+		: handle_(handle), size_(size), idx_(0), isOpen_(false), eof_(false), error_(0) {}
+
+	int open();
+	int close();
+	long write(const Exiv2::byte *data, long wcount);
+	long write(Exiv2::BasicIo &src);
+	int putb(Exiv2::byte data);
+	Exiv2::DataBuf read(long rcount);
+	long read(Exiv2::byte *buf, long rcount);
+	int getb();
+	void transfer(Exiv2::BasicIo &src);
+#if defined(_MSC_VER)
+	int seek(int64_t offset, Position pos);
+#else
+	int seek(long offset, Position pos);
+#endif
+	Exiv2::byte* mmap(bool isWriteable = false);
+	int munmap();
+	long tell() const;
+	size_t size() const;
+	bool isopen() const;
+	int error() const;
+	bool eof() const;
+	std::string path() const;
+#ifdef EXV_UNICODE_PATH
+	std::wstring wpath() const;
+#endif
+	void populateFakeData() {}
+
+private:
+	bool load();
+
+	const uintptr_t handle_;
+	const long size_;
+	long idx_;
+	bool isOpen_;
+	bool eof_;
+	int error_;
+	Exiv2::BasicIo::AutoPtr mem_;
+};
 
-void
-exiv2_image_set_exif_string(Exiv2Image *img, char *key, char *value, Exiv2Error **error)
+bool
+GoReaderIo::load()
 {
-	Exiv2::ExifData exifData = img->image->exifData();
-
-	try {
-	    Exiv2::Exifdatum& tag = exifData[key];
-		Exiv2::Value::AutoPtr valueObject = Exiv2::Value::create(Exiv2::asciiString);
-		valueObject->read(value);
-		tag.setValue(valueObject.get());
-
-		img->image->setExifData(exifData);
-		img->image->writeMetadata();
-	} catch (Exiv2::Error &e) {
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
+	if (mem_.get()) {
+		return true;
 	}
-}
 
-void
-exiv2_image_set_exif_short(Exiv2Image *img, char *key, char *value, Exiv2Error **error)
-{
-	Exiv2::ExifData exifData = img->image->exifData();
+	Exiv2::DataBuf buf(size_);
+	if (size_ > 0 && goexiv_reader_read_at(handle_, buf.pData_, size_, 0) != size_) {
+		error_ = 1;
+		return false;
+	}
 
-	try {
-		Exiv2::Exifdatum& tag = exifData[key];
-		Exiv2::Value::AutoPtr valueObject = Exiv2::Value::create(Exiv2::unsignedShort);
-		valueObject->read(value);
-		tag.setValue(valueObject.get());
+	// the MemIo constructor doesn't copy the data, while write() does
+	mem_.reset(new Exiv2::MemIo());
+	mem_->write(buf.pData_, buf.size_);
+	mem_->seek(idx_, Exiv2::BasicIo::beg);
 
-		img->image->setExifData(exifData);
-		img->image->writeMetadata();
-	} catch (Exiv2::Error &e) {
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
-	}
+	return true;
 }
 
-void
-exiv2_image_set_iptc_string(Exiv2Image *img, char *key, char *value, Exiv2Error **error)
+int
+GoReaderIo::open()
 {
-	Exiv2::IptcData iptcData = img->image->iptcData();
-
-	try {
-		Exiv2::StringValue valueObject;
-		valueObject.read(value);
-		iptcData[key] = valueObject;
-
-		img->image->setIptcData(iptcData);
-		img->image->writeMetadata();
-	} catch (Exiv2::Error &e) {
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
+	if (mem_.get()) {
+		return mem_->open();
 	}
+
+	idx_ = 0;
+	isOpen_ = true;
+	eof_ = false;
+	return 0;
 }
 
-void
-exiv2_image_set_iptc_short(Exiv2Image *img, char *key, char *value, Exiv2Error **error)
+int
+GoReaderIo::close()
 {
-    Exiv2::IptcData iptcData = img->image->iptcData();
+	if (mem_.get()) {
+		return mem_->close();
+	}
 
-    try {
-        Exiv2::Iptcdatum& tag = iptcData[key];
-        Exiv2::Value::AutoPtr valueObject = Exiv2::Value::create(Exiv2::unsignedShort);
-        valueObject->read(value);
-        tag.setValue(valueObject.get());
-
-        img->image->setIptcData(iptcData);
-        img->image->writeMetadata();
-    } catch (Exiv2::Error &e) {
-        if (error) {
-            *error = new Exiv2Error(e);
-        }
-    }
+	isOpen_ = false;
+	return 0;
 }
 
 long
-exiv_image_get_size(Exiv2Image *img)
+GoReaderIo::write(const Exiv2::byte *data, long wcount)
 {
-    return (long)img->image->io().size();
+	return load() ? mem_->write(data, wcount) : 0;
 }
 
-unsigned char*
-exiv_image_get_bytes_ptr(Exiv2Image *img)
+long
+GoReaderIo::write(Exiv2::BasicIo &src)
 {
-    return img->image->io().mmap();
+	return load() ? mem_->write(src) : 0;
 }
 
-
-DEFINE_FREE_FUNCTION(exiv2_image, Exiv2Image*);
-
-int exiv2_image_get_pixel_width(Exiv2Image *img) {
-	return img->image->pixelWidth();
-}
-
-int exiv2_image_get_pixel_height(Exiv2Image *img) {
-	return img->image->pixelHeight();
+int
+GoReaderIo::putb(Exiv2::byte data)
+{
+	return load() ? mem_->putb(data) : EOF;
 }
 
-const unsigned char* exiv2_image_icc_profile(Exiv2Image *img)
+Exiv2::DataBuf
+GoReaderIo::read(long rcount)
 {
-	if (img->image->iccProfileDefined()) {
-		return img->image->iccProfile()->pData_;
+	if (mem_.get()) {
+		return mem_->read(rcount);
 	}
-	return NULL;
-}
 
-long exiv2_image_icc_profile_size(Exiv2Image *img)
-{
-	if (img->image->iccProfileDefined()) {
-		return img->image->iccProfile()->size_;
+	if (rcount > size_) {
+		throw Exiv2::Error(Exiv2::kerInvalidMalloc);
 	}
-	return 0;
-}
 
-// XMP
-Exiv2XmpData*
-exiv2_image_get_xmp_data(const Exiv2Image *img)
-{
-	return new Exiv2XmpData(img->image->xmpData());
+	Exiv2::DataBuf buf(rcount);
+	buf.size_ = read(buf.pData_, buf.size_);
+	return buf;
 }
 
-Exiv2XmpDatum*
-exiv2_xmp_data_find_key(const Exiv2XmpData *data, const char *key, Exiv2Error **error)
+long
+GoReaderIo::read(Exiv2::byte *buf, long rcount)
 {
-	try {
-		const Exiv2::XmpData::const_iterator it = data->data.findKey(Exiv2::XmpKey(key));
-		if (it == data->data.end()) {
-			return 0;
-		}
+	if (mem_.get()) {
+		return mem_->read(buf, rcount);
+	}
 
-		return new Exiv2XmpDatum(*it);
-	} catch (Exiv2::Error &e) {
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
+	const long avail = size_ - idx_ > 0 ? size_ - idx_ : 0;
+	if (rcount > avail) {
+		rcount = avail;
+		eof_ = true;
+	}
 
+	if (rcount <= 0) {
 		return 0;
 	}
-}
-
-DEFINE_FREE_FUNCTION(exiv2_xmp_data, Exiv2XmpData*);
-
-char*
-exiv2_xmp_datum_to_string(const Exiv2XmpDatum *datum)
-{
-    Exiv2::TypeId typeId = datum->datum.typeId();
-
-    std::string strval;
 
-    if (typeId == Exiv2::xmpBag) {
-        strval = datum->datum.toString();
-    } else {
-        strval = datum->datum.toString(0);
-    }
+	const long n = goexiv_reader_read_at(handle_, buf, rcount, idx_);
+	if (n < 0) {
+		error_ = 1;
+		return 0;
+	}
 
-	return strdup(strval.c_str());
+	idx_ += n;
+	return n;
 }
 
-DEFINE_FREE_FUNCTION(exiv2_xmp_datum, Exiv2XmpDatum*);
+int
+GoReaderIo::getb()
+{
+	if (mem_.get()) {
+		return mem_->getb();
+	}
 
-// IPTC
+	Exiv2::byte b;
+	return read(&b, 1) == 1 ? b : EOF;
+}
 
-Exiv2IptcData*
-exiv2_image_get_iptc_data(const Exiv2Image *img)
+void
+GoReaderIo::transfer(Exiv2::BasicIo &src)
 {
-	return new Exiv2IptcData(img->image->iptcData());
+	if (!mem_.get()) {
+		mem_.reset(new Exiv2::MemIo());
+	}
+
+	mem_->transfer(src);
 }
 
-Exiv2IptcDatum*
-exiv2_iptc_data_find_key(const Exiv2IptcData *data, const char *key, Exiv2Error **error)
+#if defined(_MSC_VER)
+int
+GoReaderIo::seek(int64_t offset, Position pos)
+#else
+int
+GoReaderIo::seek(long offset, Position pos)
+#endif
 {
-	try {
-		const Exiv2::IptcData::const_iterator it = data->data.findKey(Exiv2::IptcKey(key));
-		if (it == data->data.end()) {
-			return 0;
-		}
+	if (mem_.get()) {
+		return mem_->seek(offset, pos);
+	}
 
-		return new Exiv2IptcDatum(*it);
-	} catch (Exiv2::Error &e) {
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
+	long base = 0;
+	switch (pos) {
+	case Exiv2::BasicIo::cur: base = idx_; break;
+	case Exiv2::BasicIo::end: base = size_; break;
+	default: break;
+	}
 
-		return 0;
+	const long newIdx = base + static_cast<long>(offset);
+	if (newIdx < 0 || newIdx > size_) {
+		return 1;
 	}
+
+	idx_ = newIdx;
+	eof_ = false;
+	return 0;
 }
 
-Exiv2IptcDatumIterator* exiv2_iptc_data_iterator(const Exiv2IptcData *data)
+Exiv2::byte*
+GoReaderIo::mmap(bool isWriteable)
 {
-	return new Exiv2IptcDatumIterator(data->data.begin(), data->data.end());
+	return load() ? mem_->mmap(isWriteable) : 0;
 }
 
-bool Exiv2IptcDatumIterator::has_next() const
+int
+GoReaderIo::munmap()
 {
-	return it != end;
+	return mem_.get() ? mem_->munmap() : 0;
 }
 
-int exiv2_iptc_data_iterator_has_next(const Exiv2IptcDatumIterator *iter)
+long
+GoReaderIo::tell() const
 {
-	return iter->has_next() ? 1 : 0;
+	return mem_.get() ? mem_->tell() : idx_;
 }
 
-Exiv2IptcDatum* Exiv2IptcDatumIterator::next()
+size_t
+GoReaderIo::size() const
 {
-	if (it == end) {
-		return 0;
-	}
-	return new Exiv2IptcDatum(*it++);
+	return mem_.get() ? mem_->size() : size_;
 }
 
-Exiv2IptcDatum* exiv2_iptc_datum_iterator_next(Exiv2IptcDatumIterator *iter)
+bool
+GoReaderIo::isopen() const
 {
-	return iter->next();
+	return mem_.get() ? mem_->isopen() : isOpen_;
 }
 
-DEFINE_FREE_FUNCTION(exiv2_iptc_data, Exiv2IptcData*);
-
-const char* exiv2_iptc_datum_key(const Exiv2IptcDatum *datum)
+int
+GoReaderIo::error() const
 {
-	return strdup(datum->datum.key().c_str());
+	return mem_.get() ? mem_->error() : error_;
 }
 
-const char* exiv2_iptc_datum_to_string(const Exiv2IptcDatum *datum)
+bool
+GoReaderIo::eof() const
 {
-	const std::string strval = datum->datum.toString();
-	return strdup(strval.c_str());
+	return mem_.get() ? mem_->eof() : eof_;
 }
 
-DEFINE_FREE_FUNCTION(exiv2_iptc_datum, Exiv2IptcDatum*);
-
-// EXIF
+std::string
+GoReaderIo::path() const
+{
+	return "io.ReaderAt";
+}
 
-Exiv2ExifData*
-exiv2_image_get_exif_data(const Exiv2Image *img)
+#ifdef EXV_UNICODE_PATH
+std::wstring
+GoReaderIo::wpath() const
 {
-	return new Exiv2ExifData(img->image->exifData());
+	return L"io.ReaderAt";
 }
+#endif
 
-Exiv2ExifDatum*
-exiv2_exif_data_find_key(const Exiv2ExifData *data, const char *key, Exiv2Error **error)
+Exiv2Image*
+exiv2_image_factory_open_reader(uintptr_t handle, long size, Exiv2Error **error)
 {
+	Exiv2Image *p = 0;
+
 	try {
-		const Exiv2::ExifData::const_iterator it = data->data.findKey(Exiv2::ExifKey(key));
-		if (it == data->data.end()) {
-			return 0;
+		Exiv2::Image::AutoPtr image = Exiv2::ImageFactory::open(Exiv2::BasicIo::AutoPtr(new GoReaderIo(handle, size)));
+		// unlike the path and bytes overloads, open(BasicIo::AutoPtr) returns 0 for unknown data
+		if (image.get() == 0) {
+			throw Exiv2::Error(Exiv2::kerMemoryContainsUnknownImageType);
 		}
 
-		return new Exiv2ExifDatum(*it);
-	} catch (Exiv2::Error &e) {
-		if (error) {
-			*error = new Exiv2Error(e);
-		}
+		p = new Exiv2Image(image);
+		return p;
+	} catch (...) {
+		delete p;
 
-		return 0;
+		set_error(error);
 	}
-}
 
-Exiv2ExifDatumIterator* exiv2_exif_data_iterator(const Exiv2ExifData *data)
-{
-	return new Exiv2ExifDatumIterator(data->data.begin(), data->data.end());
+	return 0;
 }
 
-bool Exiv2ExifDatumIterator::has_next() const
+// log_capture references the list collecting the messages logged by this thread
+// while it reads the metadata of an image, see exiv2_image_read_metadata()
+static thread_local uintptr_t log_capture = 0;
+
+struct LogCapture {
+	LogCapture(uintptr_t handle) { log_capture = handle; }
+	~LogCapture() { log_capture = 0; }
+};
+
+void
+exiv2_image_read_metadata(Exiv2Image *img, uintptr_t capture, Exiv2Error **error)
 {
-	return it != end;
+	try {
+		LogCapture guard(capture);
+		img->image->readMetadata();
+	} catch (...) {
+		set_error(error);
+	}
 }
 
-int exiv2_exif_data_iterator_has_next(const Exiv2ExifDatumIterator *iter)
+template <typename Data>
+static void
+set_metadatum_value(Data &data, const char *key, Exiv2::TypeId type, const char *value)
 {
-	return iter->has_next() ? 1 : 0;
+	Exiv2::Value::AutoPtr valueObject = Exiv2::Value::create(type);
+	valueObject->read(value);
+	data[key].setValue(valueObject.get());
 }
 
-Exiv2ExifDatum* Exiv2ExifDatumIterator::next()
+template <typename Data, typename Key>
+static int
+erase_all_metadata(Data &data, const char *key)
 {
-	if (it == end) {
-		return 0;
+	const std::string canonicalKey = Key(key).key();
+	int erased = 0;
+
+	for (typename Data::iterator it = data.begin(); it != data.end();) {
+		if (it->key() == canonicalKey) {
+			it = data.erase(it);
+			erased++;
+		} else {
+			++it;
+		}
 	}
-	return new Exiv2ExifDatum(*it++);
+
+	return erased;
 }
 
-Exiv2ExifDatum* exiv2_exif_datum_iterator_next(Exiv2ExifDatumIterator *iter)
+static void
+add_iptc_value(Exiv2::IptcData &iptcData, const char *key, Exiv2::TypeId type, const char *value)
 {
-	return iter->next();
+	const Exiv2::IptcKey iptcKey(key);
+
+	Exiv2::Value::AutoPtr valueObject = Exiv2::Value::create(type);
+	valueObject->read(value);
+
+	if (iptcData.add(iptcKey, valueObject.get()) != 0) {
+		throw Exiv2::Error(Exiv2::kerErrorMessage, "IPTC dataset is not repeatable: " + iptcKey.key());
+	}
 }
 
 void
-exiv2_exif_strip_key(Exiv2Image *img, char *key, Exiv2Error **error)
+exiv2_image_set_exif_string(Exiv2Image *img, char *key, char *value, Exiv2Error **error)
 {
-    Exiv2::ExifData exifData = img->image->exifData();
+	try {
+		Exiv2::ExifData exifData = img->image->exifData();
 
-    try {
-        Exiv2::ExifData::iterator pos = exifData.findKey(Exiv2::ExifKey(key));
-        if (pos == exifData.end()) {
-            return;
-        }
-        exifData.erase(pos);
-        img->image->setExifData(exifData);
-        img->image->writeMetadata();
-    } catch (Exiv2::Error &e) {
-        if (error) {
-            *error = new Exiv2Error(e);
-        }
-    }
+		set_metadatum_value(exifData, key, Exiv2::asciiString, value);
+
+		img->image->setExifData(exifData);
+		img->image->writeMetadata();
+	} catch (...) {
+		set_error(error);
+	}
 }
 
 void
-exiv2_iptc_strip_key(Exiv2Image *img, char *key, Exiv2Error **error)
+exiv2_image_set_exif_short(Exiv2Image *img, char *key, char *value, Exiv2Error **error)
 {
-    Exiv2::IptcData iptcData = img->image->iptcData();
+	try {
+		Exiv2::ExifData exifData = img->image->exifData();
 
-    try {
-        Exiv2::IptcData::iterator pos = iptcData.findKey(Exiv2::IptcKey(key));
-        if (pos == iptcData.end()) {
-            return;
-        }
-        iptcData.erase(pos);
-        img->image->setIptcData(iptcData);
-        img->image->writeMetadata();
-    } catch (Exiv2::Error &e) {
-        if (error) {
-            *error = new Exiv2Error(e);
-        }
-    }
+		set_metadatum_value(exifData, key, Exiv2::unsignedShort, value);
+
+		img->image->setExifData(exifData);
+		img->image->writeMetadata();
+	} catch (...) {
+		set_error(error);
+	}
 }
 
 void
-exiv2_xmp_strip_key(Exiv2Image *img, char *key, Exiv2Error **error)
+exiv2_image_set_iptc_string(Exiv2Image *img, char *key, char *value, Exiv2Error **error)
+{
+	try {
+		Exiv2::IptcData iptcData = img->image->iptcData();
+
+		set_metadatum_value(iptcData, key, Exiv2::string, value);
+
+		img->image->setIptcData(iptcData);
+		img->image->writeMetadata();
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_image_set_iptc_short(Exiv2Image *img, char *key, char *value, Exiv2Error **error)
+{
+	try {
+		Exiv2::IptcData iptcData = img->image->iptcData();
+
+		set_metadatum_value(iptcData, key, Exiv2::unsignedShort, value);
+
+		img->image->setIptcData(iptcData);
+		img->image->writeMetadata();
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+template <typename Data>
+static Data
+filter_metadata(const Data &data, const std::set<std::string> &keys)
+{
+	Data result;
+
+	for (typename Data::const_iterator it = data.begin(); it != data.end(); ++it) {
+		if (keys.count(it->key()) > 0) {
+			result.add(*it);
+		}
+	}
+
+	return result;
+}
+
+void
+exiv2_image_clear_metadata(Exiv2Image *img, const char **keep_keys, int keep_count, int keep_icc_profile, Exiv2Error **error)
+{
+	try {
+		const std::set<std::string> keys(keep_keys, keep_keys + keep_count);
+
+		const Exiv2::ExifData exifData = filter_metadata(img->image->exifData(), keys);
+		const Exiv2::IptcData iptcData = filter_metadata(img->image->iptcData(), keys);
+		const Exiv2::XmpData xmpData = filter_metadata(img->image->xmpData(), keys);
+
+		Exiv2::DataBuf iccProfile;
+		if (keep_icc_profile && img->image->iccProfileDefined()) {
+			iccProfile.alloc(img->image->iccProfile()->size_);
+			memcpy(iccProfile.pData_, img->image->iccProfile()->pData_, iccProfile.size_);
+		}
+
+		img->image->clearMetadata();
+		img->image->setExifData(exifData);
+		img->image->setIptcData(iptcData);
+		img->image->setXmpData(xmpData);
+		if (iccProfile.size_ > 0) {
+			img->image->setIccProfile(iccProfile, false);
+		}
+
+		img->image->writeMetadata();
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+static Exiv2::AccessMode
+metadata_access_mode(const Exiv2::Image &image, Exiv2::MetadataId metadataId)
+{
+	const Exiv2::AccessMode mode = image.checkMode(metadataId);
+	if (mode != Exiv2::amNone || metadataId != Exiv2::mdIccProfile) {
+		return mode;
+	}
+
+	// exiv2 doesn't register the ICC profile support of the formats, which handle it on their own
+	switch (image.imageType()) {
+	case Exiv2::ImageType::jpeg:
+	case Exiv2::ImageType::png:
+	case Exiv2::ImageType::webp:
+	case Exiv2::ImageType::jp2:
+		return Exiv2::amReadWrite;
+	default:
+		return Exiv2::amNone;
+	}
+}
+
+static bool
+can_write_metadata(const Exiv2::Image &image, Exiv2::MetadataId metadataId)
+{
+	return (metadata_access_mode(image, metadataId) & Exiv2::amWrite) != 0;
+}
+
+int
+exiv2_image_access_mode(const Exiv2Image *img, int metadata_id)
+{
+	try {
+		return metadata_access_mode(*img->image, static_cast<Exiv2::MetadataId>(metadata_id));
+	} catch (...) {
+		return Exiv2::amNone;
+	}
+}
+
+long
+exiv_image_get_size(Exiv2Image *img)
+{
+	try {
+		return (long)img->image->io().size();
+	} catch (...) {
+		return 0;
+	}
+}
+
+unsigned char*
+exiv_image_get_bytes_ptr(Exiv2Image *img, Exiv2Error **error)
+{
+	try {
+		return img->image->io().mmap();
+	} catch (...) {
+		set_error(error);
+	}
+
+	return 0;
+}
+
+
+DEFINE_FREE_FUNCTION(exiv2_image, Exiv2Image*);
+
+int exiv2_image_get_pixel_width(Exiv2Image *img) {
+	try {
+		return img->image->pixelWidth();
+	} catch (...) {
+		return 0;
+	}
+}
+
+int exiv2_image_get_pixel_height(Exiv2Image *img) {
+	try {
+		return img->image->pixelHeight();
+	} catch (...) {
+		return 0;
+	}
+}
+
+// FORMATS
+
+static Exiv2ImageFormat
+image_type_to_format(int type)
+{
+	switch (type) {
+	case Exiv2::ImageType::jpeg: return EXIV2_FORMAT_JPEG;
+	case Exiv2::ImageType::exv: return EXIV2_FORMAT_EXV;
+	case Exiv2::ImageType::cr2: return EXIV2_FORMAT_CR2;
+	case Exiv2::ImageType::crw: return EXIV2_FORMAT_CRW;
+	case Exiv2::ImageType::tiff: return EXIV2_FORMAT_TIFF;
+	case Exiv2::ImageType::mrw: return EXIV2_FORMAT_MRW;
+	case Exiv2::ImageType::orf: return EXIV2_FORMAT_ORF;
+	case Exiv2::ImageType::rw2: return EXIV2_FORMAT_RW2;
+	case Exiv2::ImageType::raf: return EXIV2_FORMAT_RAF;
+	case Exiv2::ImageType::png: return EXIV2_FORMAT_PNG;
+	case Exiv2::ImageType::gif: return EXIV2_FORMAT_GIF;
+	case Exiv2::ImageType::psd: return EXIV2_FORMAT_PSD;
+	case Exiv2::ImageType::tga: return EXIV2_FORMAT_TGA;
+	case Exiv2::ImageType::bmp: return EXIV2_FORMAT_BMP;
+	case Exiv2::ImageType::jp2: return EXIV2_FORMAT_JP2;
+	case Exiv2::ImageType::pgf: return EXIV2_FORMAT_PGF;
+	case Exiv2::ImageType::webp: return EXIV2_FORMAT_WEBP;
+	case Exiv2::ImageType::xmp: return EXIV2_FORMAT_XMP;
+	case Exiv2::ImageType::eps: return EXIV2_FORMAT_EPS;
+#ifdef EXV_ENABLE_BMFF
+	case Exiv2::ImageType::bmff: return EXIV2_FORMAT_HEIF;
+#endif
+	default: return EXIV2_FORMAT_UNKNOWN;
+	}
+}
+
+int
+exiv2_image_format(const Exiv2Image *img)
+{
+	try {
+		return image_type_to_format(img->image->imageType());
+	} catch (...) {
+		return EXIV2_FORMAT_UNKNOWN;
+	}
+}
+
+char*
+exiv2_image_mime_type(const Exiv2Image *img)
+{
+	try {
+		return strdup(img->image->mimeType().c_str());
+	} catch (...) {
+		return 0;
+	}
+}
+
+int
+exiv2_detect_format(const unsigned char *bytes, long size)
+{
+	try {
+		return image_type_to_format(Exiv2::ImageFactory::getType(bytes, size));
+	} catch (...) {
+		return EXIV2_FORMAT_UNKNOWN;
+	}
+}
+
+const unsigned char* exiv2_image_icc_profile(Exiv2Image *img)
+{
+	try {
+		if (img->image->iccProfileDefined()) {
+			return img->image->iccProfile()->pData_;
+		}
+		return NULL;
+	} catch (...) {
+		return NULL;
+	}
+}
+
+long exiv2_image_icc_profile_size(Exiv2Image *img)
+{
+	try {
+		if (img->image->iccProfileDefined()) {
+			return img->image->iccProfile()->size_;
+		}
+		return 0;
+	} catch (...) {
+		return 0;
+	}
+}
+
+// exiv2_image_exif_thumbnail returns a copy of the IFD1 thumbnail, which must be released with free(),
+// and sets mime_type to its MIME type. It returns 0 if the image has no thumbnail.
+unsigned char*
+exiv2_image_exif_thumbnail(const Exiv2Image *img, long *size, const char **mime_type, Exiv2Error **error)
+{
+	*size = 0;
+	*mime_type = "";
+
+	try {
+		const Exiv2::ExifThumbC thumb(img->image->exifData());
+		const Exiv2::DataBuf data = thumb.copy();
+		if (data.size_ <= 0) {
+			return 0;
+		}
+
+		unsigned char *buf = (unsigned char*)malloc(data.size_);
+		if (buf == 0) {
+			throw Exiv2::Error(Exiv2::kerMallocFailed);
+		}
+		memcpy(buf, data.pData_, data.size_);
+		*size = data.size_;
+		*mime_type = thumb.mimeType();
+		return buf;
+	} catch (...) {
+		set_error(error);
+		return 0;
+	}
+}
+
+// PREVIEWS
+
+Exiv2PreviewList*
+exiv2_image_get_previews(const Exiv2Image *img, Exiv2Error **error)
+{
+	try {
+		Exiv2::PreviewManager manager(*img->image);
+		return new Exiv2PreviewList(manager.getPreviewProperties());
+	} catch (...) {
+		set_error(error);
+	}
+
+	return 0;
+}
+
+int
+exiv2_preview_list_count(const Exiv2PreviewList *list)
+{
+	return list->list.size();
+}
+
+const char*
+exiv2_preview_list_mime_type(const Exiv2PreviewList *list, int n)
+{
+	return list->list[n].mimeType_.c_str();
+}
+
+const char*
+exiv2_preview_list_extension(const Exiv2PreviewList *list, int n)
+{
+	return list->list[n].extension_.c_str();
+}
+
+long
+exiv2_preview_list_width(const Exiv2PreviewList *list, int n)
+{
+	return list->list[n].width_;
+}
+
+long
+exiv2_preview_list_height(const Exiv2PreviewList *list, int n)
+{
+	return list->list[n].height_;
+}
+
+long
+exiv2_preview_list_size(const Exiv2PreviewList *list, int n)
+{
+	return list->list[n].size_;
+}
+
+DEFINE_FREE_FUNCTION(exiv2_preview_list, Exiv2PreviewList*);
+
+// exiv2_image_get_preview_bytes returns a copy of the n-th preview, which must be released with free().
+// found is set to 0 if the image has no such preview.
+unsigned char*
+exiv2_image_get_preview_bytes(const Exiv2Image *img, int n, long *size, int *found, Exiv2Error **error)
+{
+	*size = 0;
+	*found = 0;
+
+	try {
+		Exiv2::PreviewManager manager(*img->image);
+		const Exiv2::PreviewPropertiesList list = manager.getPreviewProperties();
+		if (n < 0 || n >= (int)list.size()) {
+			return 0;
+		}
+
+		const Exiv2::PreviewImage preview = manager.getPreviewImage(list[n]);
+		*found = 1;
+		if (preview.size() == 0) {
+			return 0;
+		}
+
+		unsigned char *buf = (unsigned char*)malloc(preview.size());
+		if (buf == 0) {
+			throw Exiv2::Error(Exiv2::kerMallocFailed);
+		}
+		memcpy(buf, preview.pData(), preview.size());
+		*size = preview.size();
+		return buf;
+	} catch (...) {
+		set_error(error);
+	}
+
+	return 0;
+}
+
+// TAGS
+
+struct TagDescription {
+	std::string key;
+	int id;
+	std::string group;
+	std::string name;
+	std::string label;
+	std::string description;
+	int typeId;
+	int count;
+};
+
+DEFINE_STRUCT(Exiv2TagList, std::vector<TagDescription>, list);
+
+static TagDescription
+exif_tag_description(const Exiv2::ExifKey &key)
+{
+	TagDescription d;
+	d.key = key.key();
+	d.id = key.tag();
+	d.group = key.groupName();
+	d.name = key.tagName();
+	d.label = key.tagLabel();
+	d.description = key.tagDesc();
+	d.typeId = key.defaultTypeId();
+	d.count = -1;
+
+	// the count is only available from the tag list of the group
+	const Exiv2::TagInfo *tags = Exiv2::ExifTags::tagList(d.group);
+	for (int i = 0; tags != 0 && tags[i].tag_ != 0xffff; i++) {
+		if (tags[i].tag_ == key.tag()) {
+			d.count = tags[i].count_;
+			break;
+		}
+	}
+
+	return d;
+}
+
+static TagDescription
+iptc_tag_description(const Exiv2::IptcKey &key)
+{
+	TagDescription d;
+	d.key = key.key();
+	d.id = key.tag();
+	d.group = key.recordName();
+	d.name = key.tagName();
+	d.label = key.tagLabel();
+	d.description = Exiv2::IptcDataSets::dataSetDesc(key.tag(), key.record());
+	d.typeId = Exiv2::IptcDataSets::dataSetType(key.tag(), key.record());
+	d.count = -1;
+	return d;
+}
+
+static TagDescription
+xmp_tag_description(const Exiv2::XmpKey &key)
+{
+	// XmpKey accepts any property name of a registered namespace
+	if (Exiv2::XmpProperties::propertyInfo(key) == 0) {
+		throw Exiv2::Error(Exiv2::kerInvalidKey, key.key());
+	}
+
+	const char *desc = Exiv2::XmpProperties::propertyDesc(key);
+
+	TagDescription d;
+	d.key = key.key();
+	d.id = 0;
+	d.group = key.groupName();
+	d.name = key.tagName();
+	d.label = key.tagLabel();
+	d.description = desc ? desc : "";
+	d.typeId = Exiv2::XmpProperties::propertyType(key);
+	d.count = -1;
+	return d;
+}
+
+// split_key splits "Family.Rest" into the family and the rest
+static std::string
+split_key(const std::string &key, std::string &rest)
+{
+	const std::string::size_type pos = key.find('.');
+	if (pos == std::string::npos) {
+		throw Exiv2::Error(Exiv2::kerInvalidKey, key);
+	}
+
+	rest = key.substr(pos + 1);
+	return key.substr(0, pos);
+}
+
+Exiv2TagList*
+exiv2_lookup_tag(const char *key, Exiv2Error **error)
+{
+	try {
+		std::string rest;
+		const std::string family = split_key(key, rest);
+
+		std::vector<TagDescription> list;
+		if (family == "Exif") {
+			list.push_back(exif_tag_description(Exiv2::ExifKey(key)));
+		} else if (family == "Iptc") {
+			list.push_back(iptc_tag_description(Exiv2::IptcKey(key)));
+		} else if (family == "Xmp") {
+			list.push_back(xmp_tag_description(Exiv2::XmpKey(key)));
+		} else {
+			throw Exiv2::Error(Exiv2::kerInvalidKey, key);
+		}
+
+		return new Exiv2TagList(list);
+	} catch (...) {
+		set_error(error);
+	}
+
+	return 0;
+}
+
+Exiv2TagList*
+exiv2_list_tags(const char *group, Exiv2Error **error)
+{
+	try {
+		std::string name;
+		const std::string family = split_key(group, name);
+
+		std::vector<TagDescription> list;
+		if (family == "Exif") {
+			const Exiv2::TagInfo *tags = Exiv2::ExifTags::tagList(name);
+			if (tags == 0) {
+				throw Exiv2::Error(Exiv2::kerInvalidKey, group);
+			}
+			for (int i = 0; tags[i].tag_ != 0xffff; i++) {
+				list.push_back(exif_tag_description(Exiv2::ExifKey(tags[i].tag_, name)));
+			}
+		} else if (family == "Iptc") {
+			const uint16_t record = Exiv2::IptcDataSets::recordId(name);
+			const Exiv2::DataSet *datasets;
+			if (record == Exiv2::IptcDataSets::envelope) {
+				datasets = Exiv2::IptcDataSets::envelopeRecordList();
+			} else if (record == Exiv2::IptcDataSets::application2) {
+				datasets = Exiv2::IptcDataSets::application2RecordList();
+			} else {
+				throw Exiv2::Error(Exiv2::kerInvalidRecord, name);
+			}
+			for (int i = 0; datasets[i].number_ != 0xffff; i++) {
+				list.push_back(iptc_tag_description(Exiv2::IptcKey(datasets[i].number_, record)));
+			}
+		} else if (family == "Xmp") {
+			const Exiv2::XmpPropertyInfo *props = Exiv2::XmpProperties::propertyList(name);
+			for (int i = 0; props != 0 && props[i].name_ != 0; i++) {
+				list.push_back(xmp_tag_description(Exiv2::XmpKey(name, props[i].name_)));
+			}
+		} else {
+			throw Exiv2::Error(Exiv2::kerInvalidKey, group);
+		}
+
+		return new Exiv2TagList(list);
+	} catch (...) {
+		set_error(error);
+	}
+
+	return 0;
+}
+
+int
+exiv2_tag_list_count(const Exiv2TagList *list)
+{
+	return list->list.size();
+}
+
+const char*
+exiv2_tag_list_key(const Exiv2TagList *list, int n)
+{
+	return list->list[n].key.c_str();
+}
+
+int
+exiv2_tag_list_id(const Exiv2TagList *list, int n)
+{
+	return list->list[n].id;
+}
+
+const char*
+exiv2_tag_list_group(const Exiv2TagList *list, int n)
+{
+	return list->list[n].group.c_str();
+}
+
+const char*
+exiv2_tag_list_name(const Exiv2TagList *list, int n)
+{
+	return list->list[n].name.c_str();
+}
+
+const char*
+exiv2_tag_list_label(const Exiv2TagList *list, int n)
+{
+	return list->list[n].label.c_str();
+}
+
+const char*
+exiv2_tag_list_description(const Exiv2TagList *list, int n)
+{
+	return list->list[n].description.c_str();
+}
+
+int
+exiv2_tag_list_type_id(const Exiv2TagList *list, int n)
+{
+	return list->list[n].typeId;
+}
+
+int
+exiv2_tag_list_value_count(const Exiv2TagList *list, int n)
+{
+	return list->list[n].count;
+}
+
+DEFINE_FREE_FUNCTION(exiv2_tag_list, Exiv2TagList*);
+
+// VALUES
+
+static int
+metadatum_to_rational(const Exiv2::Metadatum &datum, long n, long long *numerator, long long *denominator)
+{
+	try {
+		const Exiv2::Value &value = datum.value();
+
+		// Exiv2::Value::toRational() truncates unsigned rationals to int32
+		if (value.typeId() == Exiv2::unsignedRational) {
+			const Exiv2::URationalValue *urational = dynamic_cast<const Exiv2::URationalValue*>(&value);
+			if (urational != 0) {
+				const Exiv2::URational r = urational->value_.at(n);
+				*numerator = r.first;
+				*denominator = r.second;
+				return 1;
+			}
+		}
+
+		const Exiv2::Rational r = value.toRational(n);
+		if (!value.ok()) {
+			return 0;
+		}
+
+		*numerator = r.first;
+		*denominator = r.second;
+		return 1;
+	} catch (...) {
+		return 0;
+	}
+}
+
+static long
+metadatum_to_long(const Exiv2::Metadatum &datum, long n, int *ok)
+{
+	try {
+		const Exiv2::Value &value = datum.value();
+		const long result = value.toLong(n);
+		*ok = value.ok() ? 1 : 0;
+		return result;
+	} catch (...) {
+		*ok = 0;
+		return 0;
+	}
+}
+
+static double
+metadatum_to_double(const Exiv2::Metadatum &datum, long n, int *ok)
+{
+	long long numerator, denominator;
+
+	switch (datum.typeId()) {
+	case Exiv2::unsignedRational:
+	case Exiv2::signedRational:
+		if (!metadatum_to_rational(datum, n, &numerator, &denominator) || denominator == 0) {
+			*ok = 0;
+			return 0;
+		}
+		*ok = 1;
+		return (double)numerator / denominator;
+	default:
+		break;
+	}
+
+	try {
+		const Exiv2::Value &value = datum.value();
+
+		// Exiv2::Value::toFloat() loses the precision of doubles
+		if (value.typeId() == Exiv2::tiffDouble) {
+			const Exiv2::DoubleValue *dvalue = dynamic_cast<const Exiv2::DoubleValue*>(&value);
+			if (dvalue != 0) {
+				*ok = 1;
+				return dvalue->value_.at(n);
+			}
+		}
+
+		const double result = value.toFloat(n);
+		*ok = value.ok() ? 1 : 0;
+		return result;
+	} catch (...) {
+		*ok = 0;
+		return 0;
+	}
+}
+
+// metadatum_to_bytes returns a copy of the value, which must be released with free(),
+// or 0 if the value is empty or can't be copied
+static unsigned char*
+metadatum_to_bytes(const Exiv2::Metadatum &datum, long *size)
+{
+	*size = 0;
+	unsigned char *buf = 0;
+
+	try {
+		const Exiv2::Value &value = datum.value();
+		const long len = value.size();
+		if (len <= 0) {
+			return 0;
+		}
+
+		buf = (unsigned char*)malloc(len);
+		if (buf == 0) {
+			return 0;
+		}
+
+		*size = value.copy(buf, Exiv2::littleEndian);
+		return buf;
+	} catch (...) {
+		free(buf);
+		*size = 0;
+		return 0;
+	}
+}
+
+#define DEFINE_VALUE_FUNCTIONS(name,type) \
+int name##_type_id(const type *x) \
+{ \
+	return x->datum.typeId(); \
+} \
+const char* name##_type_name(const type *x) \
+{ \
+	return x->datum.typeName(); \
+} \
+long name##_count(const type *x) \
+{ \
+	return x->datum.count(); \
+} \
+long name##_size(const type *x) \
+{ \
+	return x->datum.size(); \
+} \
+long name##_to_long(const type *x, long n, int *ok) \
+{ \
+	return metadatum_to_long(x->datum, n, ok); \
+} \
+double name##_to_double(const type *x, long n, int *ok) \
+{ \
+	return metadatum_to_double(x->datum, n, ok); \
+} \
+int name##_to_rational(const type *x, long n, long long *numerator, long long *denominator) \
+{ \
+	return metadatum_to_rational(x->datum, n, numerator, denominator); \
+} \
+unsigned char* name##_to_bytes(const type *x, long *size) \
+{ \
+	return metadatum_to_bytes(x->datum, size); \
+}
+
+// XMP
+Exiv2XmpData*
+exiv2_image_get_xmp_data(const Exiv2Image *img)
+{
+	try {
+		return new Exiv2XmpData(img->image->xmpData());
+	} catch (...) {
+		return 0;
+	}
+}
+
+Exiv2XmpDatum*
+exiv2_xmp_data_find_key(const Exiv2XmpData *data, const char *key, Exiv2Error **error)
+{
+	try {
+		const Exiv2::XmpData::const_iterator it = data->data.findKey(Exiv2::XmpKey(key));
+		if (it == data->data.end()) {
+			return 0;
+		}
+
+		return new Exiv2XmpDatum(*it);
+	} catch (...) {
+		set_error(error);
+
+		return 0;
+	}
+}
+
+Exiv2XmpDatumIterator* exiv2_xmp_data_iterator(const Exiv2XmpData *data)
+{
+	try {
+		return new Exiv2XmpDatumIterator(data->data.begin(), data->data.end());
+	} catch (...) {
+		return 0;
+	}
+}
+
+bool Exiv2XmpDatumIterator::has_next() const
+{
+	return it != end;
+}
+
+int exiv2_xmp_data_iterator_has_next(const Exiv2XmpDatumIterator *iter)
+{
+	return iter->has_next() ? 1 : 0;
+}
+
+Exiv2XmpDatum* Exiv2XmpDatumIterator::next()
+{
+	if (it == end) {
+		return 0;
+	}
+	return new Exiv2XmpDatum(*it++);
+}
+
+Exiv2XmpDatum* exiv2_xmp_datum_iterator_next(Exiv2XmpDatumIterator *iter)
+{
+	try {
+		return iter->next();
+	} catch (...) {
+		return 0;
+	}
+}
+
+DEFINE_FREE_FUNCTION(exiv2_xmp_data, Exiv2XmpData*);
+
+const char* exiv2_xmp_datum_key(const Exiv2XmpDatum *datum)
+{
+	try {
+		return strdup(datum->datum.key().c_str());
+	} catch (...) {
+		return 0;
+	}
+}
+
+char*
+exiv2_xmp_datum_to_string(const Exiv2XmpDatum *datum)
+{
+    Exiv2::TypeId typeId = datum->datum.typeId();
+
+    std::string strval;
+
+    try {
+        if (typeId == Exiv2::xmpBag) {
+            strval = datum->datum.toString();
+        } else {
+            strval = datum->datum.toString(0);
+        }
+    } catch (...) {
+        return 0;
+    }
+
+	return strdup(strval.c_str());
+}
+
+DEFINE_FREE_FUNCTION(exiv2_xmp_datum, Exiv2XmpDatum*);
+
+char*
+exiv2_xmp_datum_print(const Exiv2XmpDatum *datum)
+{
+	try {
+		return strdup(datum->datum.print().c_str());
+	} catch (...) {
+		return 0;
+	}
+}
+DEFINE_VALUE_FUNCTIONS(exiv2_xmp_datum, Exiv2XmpDatum);
+
+// IPTC
+
+Exiv2IptcData*
+exiv2_image_get_iptc_data(const Exiv2Image *img)
+{
+	try {
+		return new Exiv2IptcData(img->image->iptcData());
+	} catch (...) {
+		return 0;
+	}
+}
+
+Exiv2IptcDatum*
+exiv2_iptc_data_find_key(const Exiv2IptcData *data, const char *key, Exiv2Error **error)
+{
+	try {
+		const Exiv2::IptcData::const_iterator it = data->data.findKey(Exiv2::IptcKey(key));
+		if (it == data->data.end()) {
+			return 0;
+		}
+
+		return new Exiv2IptcDatum(*it);
+	} catch (...) {
+		set_error(error);
+
+		return 0;
+	}
+}
+
+char*
+exiv2_iptc_key_normalize(const char *key, Exiv2Error **error)
+{
+	try {
+		return strdup(Exiv2::IptcKey(key).key().c_str());
+	} catch (...) {
+		set_error(error);
+
+		return 0;
+	}
+}
+
+Exiv2IptcDatumIterator* exiv2_iptc_data_iterator(const Exiv2IptcData *data)
+{
+	try {
+		return new Exiv2IptcDatumIterator(data->data.begin(), data->data.end());
+	} catch (...) {
+		return 0;
+	}
+}
+
+bool Exiv2IptcDatumIterator::has_next() const
+{
+	return it != end;
+}
+
+int exiv2_iptc_data_iterator_has_next(const Exiv2IptcDatumIterator *iter)
+{
+	return iter->has_next() ? 1 : 0;
+}
+
+Exiv2IptcDatum* Exiv2IptcDatumIterator::next()
+{
+	if (it == end) {
+		return 0;
+	}
+	return new Exiv2IptcDatum(*it++);
+}
+
+Exiv2IptcDatum* exiv2_iptc_datum_iterator_next(Exiv2IptcDatumIterator *iter)
+{
+	try {
+		return iter->next();
+	} catch (...) {
+		return 0;
+	}
+}
+
+DEFINE_FREE_FUNCTION(exiv2_iptc_data, Exiv2IptcData*);
+
+const char* exiv2_iptc_datum_key(const Exiv2IptcDatum *datum)
+{
+	try {
+		return strdup(datum->datum.key().c_str());
+	} catch (...) {
+		return 0;
+	}
+}
+
+const char* exiv2_iptc_datum_to_string(const Exiv2IptcDatum *datum)
+{
+	try {
+		const std::string strval = datum->datum.toString();
+		return strdup(strval.c_str());
+	} catch (...) {
+		return 0;
+	}
+}
+
+DEFINE_FREE_FUNCTION(exiv2_iptc_datum, Exiv2IptcDatum*);
+
+char*
+exiv2_iptc_datum_print(const Exiv2IptcDatum *datum)
 {
-    Exiv2::XmpData xmpData = img->image->xmpData();
+	try {
+		return strdup(datum->datum.print().c_str());
+	} catch (...) {
+		return 0;
+	}
+}
+DEFINE_VALUE_FUNCTIONS(exiv2_iptc_datum, Exiv2IptcDatum);
 
-    try {
-        Exiv2::XmpData::iterator pos = xmpData.findKey(Exiv2::XmpKey(key));
-        if (pos == xmpData.end()) {
-            return;
-        }
-        xmpData.erase(pos);
-        img->image->setXmpData(xmpData);
-        img->image->writeMetadata();
-    } catch (Exiv2::Error &e) {
-        if (error) {
-            *error = new Exiv2Error(e);
-        }
-    }
+// EXIF
+
+Exiv2ExifData*
+exiv2_image_get_exif_data(const Exiv2Image *img)
+{
+	try {
+		return new Exiv2ExifData(img->image->exifData());
+	} catch (...) {
+		return 0;
+	}
+}
+
+Exiv2ExifDatum*
+exiv2_exif_data_find_key(const Exiv2ExifData *data, const char *key, Exiv2Error **error)
+{
+	try {
+		const Exiv2::ExifData::const_iterator it = data->data.findKey(Exiv2::ExifKey(key));
+		if (it == data->data.end()) {
+			return 0;
+		}
+
+		return new Exiv2ExifDatum(*it);
+	} catch (...) {
+		set_error(error);
+
+		return 0;
+	}
+}
+
+Exiv2ExifDatumIterator* exiv2_exif_data_iterator(const Exiv2ExifData *data)
+{
+	try {
+		return new Exiv2ExifDatumIterator(data->data.begin(), data->data.end());
+	} catch (...) {
+		return 0;
+	}
+}
+
+bool Exiv2ExifDatumIterator::has_next() const
+{
+	return it != end;
+}
+
+int exiv2_exif_data_iterator_has_next(const Exiv2ExifDatumIterator *iter)
+{
+	return iter->has_next() ? 1 : 0;
+}
+
+Exiv2ExifDatum* Exiv2ExifDatumIterator::next()
+{
+	if (it == end) {
+		return 0;
+	}
+	return new Exiv2ExifDatum(*it++);
+}
+
+Exiv2ExifDatum* exiv2_exif_datum_iterator_next(Exiv2ExifDatumIterator *iter)
+{
+	try {
+		return iter->next();
+	} catch (...) {
+		return 0;
+	}
+}
+
+void
+exiv2_exif_strip_key(Exiv2Image *img, char *key, Exiv2Error **error)
+{
+	try {
+		Exiv2::ExifData exifData = img->image->exifData();
+
+		if (erase_all_metadata<Exiv2::ExifData, Exiv2::ExifKey>(exifData, key) == 0) {
+			return;
+		}
+		img->image->setExifData(exifData);
+		img->image->writeMetadata();
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_iptc_strip_key(Exiv2Image *img, char *key, Exiv2Error **error)
+{
+	try {
+		Exiv2::IptcData iptcData = img->image->iptcData();
+
+		if (erase_all_metadata<Exiv2::IptcData, Exiv2::IptcKey>(iptcData, key) == 0) {
+			return;
+		}
+		img->image->setIptcData(iptcData);
+		img->image->writeMetadata();
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_xmp_strip_key(Exiv2Image *img, char *key, Exiv2Error **error)
+{
+	try {
+		Exiv2::XmpData xmpData = img->image->xmpData();
+
+		if (erase_all_metadata<Exiv2::XmpData, Exiv2::XmpKey>(xmpData, key) == 0) {
+			return;
+		}
+		img->image->setXmpData(xmpData);
+		img->image->writeMetadata();
+	} catch (...) {
+		set_error(error);
+	}
 }
 
 DEFINE_FREE_FUNCTION(exiv2_exif_data, Exiv2ExifData*);
 
 const char* exiv2_exif_datum_key(const Exiv2ExifDatum *datum)
 {
-	return strdup(datum->datum.key().c_str());
+	try {
+		return strdup(datum->datum.key().c_str());
+	} catch (...) {
+		return 0;
+	}
 }
 
 const char* exiv2_exif_datum_to_string(const Exiv2ExifDatum *datum)
 {
-	const std::string strval = datum->datum.toString();
-	return strdup(strval.c_str());
+	try {
+		const std::string strval = datum->datum.toString();
+		return strdup(strval.c_str());
+	} catch (...) {
+		return 0;
+	}
 }
 
 DEFINE_FREE_FUNCTION(exiv2_exif_datum, Exiv2ExifDatum*);
 
+// exiv2_exif_datum_print passes the whole data to Exifdatum::print(), which some
+// MakerNote tags (e.g. lens types) need to be decoded
+char*
+exiv2_exif_datum_print(const Exiv2ExifDatum *datum, const Exiv2ExifData *data)
+{
+	try {
+		return strdup(datum->datum.print(&data->data).c_str());
+	} catch (...) {
+		return 0;
+	}
+}
+DEFINE_VALUE_FUNCTIONS(exiv2_exif_datum, Exiv2ExifDatum);
+
+// TRANSACTIONS
+
+struct _Exiv2MetadataTx {
+	_Exiv2MetadataTx(Exiv2Image *img)
+		: img(img)
+		, exifData(img->image->exifData())
+		, iptcData(img->image->iptcData())
+		, xmpData(img->image->xmpData())
+		, exifModified(false)
+		, iptcModified(false)
+		, xmpModified(false)
+		, commentModified(false)
+		, iccProfileModified(false) {}
+
+	Exiv2Image *img;
+
+	Exiv2::ExifData exifData;
+	Exiv2::IptcData iptcData;
+	Exiv2::XmpData xmpData;
+
+	bool exifModified;
+	bool iptcModified;
+	bool xmpModified;
+
+	// the comment and the ICC profile are staged only when they are copied from another image
+	std::string comment;
+	std::string iccProfile;
+	bool commentModified;
+	bool iccProfileModified;
+};
+
+Exiv2MetadataTx*
+exiv2_image_edit(Exiv2Image *img, Exiv2Error **error)
+{
+	try {
+		return new Exiv2MetadataTx(img);
+	} catch (...) {
+		set_error(error);
+		return 0;
+	}
+}
+
+void
+exiv2_metadata_tx_set_exif(Exiv2MetadataTx *tx, const char *key, int type, const char *value, Exiv2Error **error)
+{
+	try {
+		set_metadatum_value(tx->exifData, key, static_cast<Exiv2::TypeId>(type), value);
+		tx->exifModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_set_iptc(Exiv2MetadataTx *tx, const char *key, int type, const char *value, Exiv2Error **error)
+{
+	try {
+		set_metadatum_value(tx->iptcData, key, static_cast<Exiv2::TypeId>(type), value);
+		tx->iptcModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_add_iptc(Exiv2MetadataTx *tx, const char *key, int type, const char *value, Exiv2Error **error)
+{
+	try {
+		add_iptc_value(tx->iptcData, key, static_cast<Exiv2::TypeId>(type), value);
+		tx->iptcModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_set_iptc_strings(Exiv2MetadataTx *tx, const char *key, const char **values, int count, Exiv2Error **error)
+{
+	try {
+		Exiv2::IptcData iptcData = tx->iptcData;
+
+		erase_all_metadata<Exiv2::IptcData, Exiv2::IptcKey>(iptcData, key);
+		for (int i = 0; i < count; i++) {
+			add_iptc_value(iptcData, key, Exiv2::string, values[i]);
+		}
+
+		tx->iptcData = iptcData;
+		tx->iptcModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_set_xmp(Exiv2MetadataTx *tx, const char *key, int type, const char *value, Exiv2Error **error)
+{
+	try {
+		set_metadatum_value(tx->xmpData, key, static_cast<Exiv2::TypeId>(type), value);
+		tx->xmpModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_set_xmp_array(Exiv2MetadataTx *tx, const char *key, int type, const char **values, int count, Exiv2Error **error)
+{
+	try {
+		Exiv2::Value::AutoPtr valueObject = Exiv2::Value::create(static_cast<Exiv2::TypeId>(type));
+		for (int i = 0; i < count; i++) {
+			// every read() appends an item to an XMP array
+			valueObject->read(values[i]);
+		}
+
+		tx->xmpData[key].setValue(valueObject.get());
+		tx->xmpModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_set_xmp_lang_alt(Exiv2MetadataTx *tx, const char *key, const char **langs, const char **values, int count, Exiv2Error **error)
+{
+	try {
+		Exiv2::LangAltValue valueObject;
+		for (int i = 0; i < count; i++) {
+			valueObject.value_[langs[i]] = values[i];
+		}
+
+		tx->xmpData[key].setValue(&valueObject);
+		tx->xmpModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_exif_strip_key(Exiv2MetadataTx *tx, const char *key, Exiv2Error **error)
+{
+	try {
+		if (erase_all_metadata<Exiv2::ExifData, Exiv2::ExifKey>(tx->exifData, key) > 0) {
+			tx->exifModified = true;
+		}
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_iptc_strip_key(Exiv2MetadataTx *tx, const char *key, Exiv2Error **error)
+{
+	try {
+		if (erase_all_metadata<Exiv2::IptcData, Exiv2::IptcKey>(tx->iptcData, key) > 0) {
+			tx->iptcModified = true;
+		}
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_xmp_strip_key(Exiv2MetadataTx *tx, const char *key, Exiv2Error **error)
+{
+	try {
+		if (erase_all_metadata<Exiv2::XmpData, Exiv2::XmpKey>(tx->xmpData, key) > 0) {
+			tx->xmpModified = true;
+		}
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_set_exif_thumbnail(Exiv2MetadataTx *tx, const unsigned char *jpeg, long size, Exiv2Error **error)
+{
+	try {
+		Exiv2::ExifThumb thumb(tx->exifData);
+		thumb.setJpegThumbnail(jpeg, size);
+		tx->exifModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+void
+exiv2_metadata_tx_erase_exif_thumbnail(Exiv2MetadataTx *tx, Exiv2Error **error)
+{
+	try {
+		Exiv2::ExifThumbC current(tx->exifData);
+		if (current.mimeType()[0] == '\0') {
+			return;
+		}
+
+		Exiv2::ExifThumb thumb(tx->exifData);
+		thumb.erase();
+		tx->exifModified = true;
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+// exiv2_metadata_tx_copy_metadata stages the metadata of src selected by the flags.
+// The kinds of metadata the format of the transaction image can't hold are skipped.
+void
+exiv2_metadata_tx_copy_metadata(Exiv2MetadataTx *tx, const Exiv2Image *src, int exif, int iptc, int xmp, int icc_profile, int comment, Exiv2Error **error)
+{
+	try {
+		const Exiv2::Image &dst = *tx->img->image;
+
+		if (exif && can_write_metadata(dst, Exiv2::mdExif)) {
+			tx->exifData = src->image->exifData();
+			tx->exifModified = true;
+		}
+		if (iptc && can_write_metadata(dst, Exiv2::mdIptc)) {
+			tx->iptcData = src->image->iptcData();
+			tx->iptcModified = true;
+		}
+		if (xmp && can_write_metadata(dst, Exiv2::mdXmp)) {
+			tx->xmpData = src->image->xmpData();
+			tx->xmpModified = true;
+		}
+		if (comment && can_write_metadata(dst, Exiv2::mdComment)) {
+			tx->comment = src->image->comment();
+			tx->commentModified = true;
+		}
+		if (icc_profile && src->image->iccProfileDefined() && can_write_metadata(dst, Exiv2::mdIccProfile)) {
+			const Exiv2::DataBuf *profile = src->image->iccProfile();
+			tx->iccProfile.assign(reinterpret_cast<const char*>(profile->pData_), profile->size_);
+			tx->iccProfileModified = true;
+		}
+	} catch (...) {
+		set_error(error);
+	}
+}
+
+static void
+set_icc_profile(Exiv2::Image &image, const std::string &profile)
+{
+	Exiv2::DataBuf buf(reinterpret_cast<const Exiv2::byte*>(profile.data()), profile.size());
+	image.setIccProfile(buf, false);
+}
+
+void
+exiv2_metadata_tx_commit(Exiv2MetadataTx *tx, Exiv2Error **error)
+{
+	if (!tx->exifModified && !tx->iptcModified && !tx->xmpModified && !tx->commentModified && !tx->iccProfileModified) {
+		return;
+	}
+
+	Exiv2::Image &image = *tx->img->image;
+
+	// the image keeps its previous metadata if the write fails
+	Exiv2::ExifData exifData;
+	Exiv2::IptcData iptcData;
+	Exiv2::XmpData xmpData;
+	std::string comment;
+	std::string iccProfile;
+	bool iccProfileDefined = false;
+
+	try {
+		exifData = image.exifData();
+		iptcData = image.iptcData();
+		xmpData = image.xmpData();
+		comment = image.comment();
+		iccProfileDefined = image.iccProfileDefined();
+		if (iccProfileDefined) {
+			iccProfile.assign(reinterpret_cast<const char*>(image.iccProfile()->pData_), image.iccProfile()->size_);
+		}
+	} catch (...) {
+		set_error(error);
+		return;
+	}
+
+	try {
+		if (tx->exifModified) {
+			image.setExifData(tx->exifData);
+		}
+		if (tx->iptcModified) {
+			image.setIptcData(tx->iptcData);
+		}
+		if (tx->xmpModified) {
+			image.setXmpData(tx->xmpData);
+		}
+		if (tx->commentModified) {
+			image.setComment(tx->comment);
+		}
+		if (tx->iccProfileModified) {
+			set_icc_profile(image, tx->iccProfile);
+		}
+		image.writeMetadata();
+
+		tx->exifModified = tx->iptcModified = tx->xmpModified = false;
+		tx->commentModified = tx->iccProfileModified = false;
+	} catch (...) {
+		set_error(error);
+
+		try {
+			image.setExifData(exifData);
+			image.setIptcData(iptcData);
+			image.setXmpData(xmpData);
+			if (tx->commentModified) {
+				image.setComment(comment);
+			}
+			if (tx->iccProfileModified) {
+				if (iccProfileDefined) {
+					set_icc_profile(image, iccProfile);
+				} else {
+					image.clearIccProfile();
+				}
+			}
+		} catch (...) {
+		}
+	}
+}
+
+DEFINE_FREE_FUNCTION(exiv2_metadata_tx, Exiv2MetadataTx*);
+
 // LOG LEVEL
 
 void
@@ -485,6 +1935,34 @@ exiv2_log_msg_set_level(const int level)
     Exiv2::LogMsg::setLevel(cpplevel);
 }
 
+static std::atomic<bool> go_log_handler(false);
+
+static void
+log_handler(int level, const char *msg)
+{
+	if (log_capture != 0 && level >= Exiv2::LogMsg::warn) {
+		goexiv_log_capture(log_capture, level, const_cast<char*>(msg));
+	}
+
+	if (go_log_handler) {
+		goexiv_log_message(level, const_cast<char*>(msg));
+	} else {
+		Exiv2::LogMsg::defaultHandler(level, msg);
+	}
+}
+
+void
+exiv2_log_msg_init()
+{
+	Exiv2::LogMsg::setHandler(log_handler);
+}
+
+void
+exiv2_log_msg_set_go_handler(int enabled)
+{
+	go_log_handler = enabled != 0;
+}
+
 // ERRORS
 
 int
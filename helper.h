@@ -1,65 +1,148 @@
+#ifndef GOEXIV_HELPER_H
+#define GOEXIV_HELPER_H
+
+#include <stdint.h>
+
 #ifdef __cplusplus
 extern "C" {
 #endif
 
 #define DECLARE_STRUCT(name) typedef struct _##name name
 
+// Error codes of the C++ exceptions which are not Exiv2::Error, see ErrorCodeStdException and
+// ErrorCodeUnknownException in errors.go
+#define GOEXIV_ERROR_STD_EXCEPTION 1004
+#define GOEXIV_ERROR_UNKNOWN_EXCEPTION 1005
+
+// Exiv2ImageFormat is a stable counterpart of Exiv2::ImageType, whose values vary between exiv2 versions
+typedef enum {
+	EXIV2_FORMAT_UNKNOWN = 0,
+	EXIV2_FORMAT_JPEG,
+	EXIV2_FORMAT_EXV,
+	EXIV2_FORMAT_CR2,
+	EXIV2_FORMAT_CRW,
+	EXIV2_FORMAT_TIFF,
+	EXIV2_FORMAT_MRW,
+	EXIV2_FORMAT_ORF,
+	EXIV2_FORMAT_RW2,
+	EXIV2_FORMAT_RAF,
+	EXIV2_FORMAT_PNG,
+	EXIV2_FORMAT_GIF,
+	EXIV2_FORMAT_PSD,
+	EXIV2_FORMAT_TGA,
+	EXIV2_FORMAT_BMP,
+	EXIV2_FORMAT_JP2,
+	EXIV2_FORMAT_PGF,
+	EXIV2_FORMAT_WEBP,
+	EXIV2_FORMAT_XMP,
+	EXIV2_FORMAT_EPS,
+	EXIV2_FORMAT_HEIF
+} Exiv2ImageFormat;
+
 DECLARE_STRUCT(Exiv2ImageFactory);
 DECLARE_STRUCT(Exiv2Image);
 DECLARE_STRUCT(Exiv2XmpData);
 DECLARE_STRUCT(Exiv2XmpDatum);
+DECLARE_STRUCT(Exiv2XmpDatumIterator);
 DECLARE_STRUCT(Exiv2IptcData);
 DECLARE_STRUCT(Exiv2IptcDatum);
 DECLARE_STRUCT(Exiv2IptcDatumIterator);
 DECLARE_STRUCT(Exiv2ExifData);
 DECLARE_STRUCT(Exiv2ExifDatum);
 DECLARE_STRUCT(Exiv2ExifDatumIterator);
+DECLARE_STRUCT(Exiv2MetadataTx);
+DECLARE_STRUCT(Exiv2PreviewList);
+DECLARE_STRUCT(Exiv2TagList);
 DECLARE_STRUCT(Exiv2Error);
 
+void exiv2_xmp_datum_iterator_free(Exiv2XmpDatumIterator *datum);
 void exiv2_iptc_datum_iterator_free(Exiv2IptcDatumIterator *datum);
 void exiv2_exif_datum_iterator_free(Exiv2ExifDatumIterator *datum);
 
 Exiv2Image* exiv2_image_factory_open(const char *path, Exiv2Error **error);
 Exiv2Image* exiv2_image_factory_open_bytes(const unsigned char *path, long size, Exiv2Error **error);
+Exiv2Image* exiv2_image_factory_open_reader(uintptr_t handle, long size, Exiv2Error **error);
+
+// goexiv_reader_read_at is implemented in Go. It reads size bytes at offset from the io.ReaderAt
+// referenced by handle and returns the number of bytes read or -1 on error.
+long goexiv_reader_read_at(uintptr_t handle, unsigned char *buf, long size, long offset);
 
 long exiv_image_get_size(Exiv2Image *img);
-unsigned char* exiv_image_get_bytes_ptr(Exiv2Image *img);
+unsigned char* exiv_image_get_bytes_ptr(Exiv2Image *img, Exiv2Error **error);
 
-void exiv2_image_read_metadata(Exiv2Image *img, Exiv2Error **error);
+void exiv2_image_read_metadata(Exiv2Image *img, uintptr_t capture, Exiv2Error **error);
 void exiv2_image_set_exif_string(Exiv2Image *img, char *key, char *value, Exiv2Error **error);
 void exiv2_image_set_exif_short(Exiv2Image *img, char *key, char *value, Exiv2Error **error);
 void exiv2_image_set_iptc_string(Exiv2Image *img, char *key, char *value, Exiv2Error **error);
 void exiv2_image_set_iptc_short(Exiv2Image *img, char *key, char *value, Exiv2Error **error);
+void exiv2_image_clear_metadata(Exiv2Image *img, const char **keep_keys, int keep_count, int keep_icc_profile, Exiv2Error **error);
+int exiv2_image_access_mode(const Exiv2Image *img, int metadata_id);
 void exiv2_image_free(Exiv2Image *img);
 
+int exiv2_image_format(const Exiv2Image *img);
+char* exiv2_image_mime_type(const Exiv2Image *img);
+int exiv2_detect_format(const unsigned char *bytes, long size);
+
 int exiv2_image_get_pixel_width(Exiv2Image *img);
 int exiv2_image_get_pixel_height(Exiv2Image *img);
 
 Exiv2XmpData* exiv2_image_get_xmp_data(const Exiv2Image *img);
 void exiv2_xmp_data_free(Exiv2XmpData *data);
+const char* exiv2_xmp_datum_key(const Exiv2XmpDatum *datum);
 char* exiv2_xmp_datum_to_string(const Exiv2XmpDatum *datum);
+char* exiv2_xmp_datum_print(const Exiv2XmpDatum *datum);
 void exiv2_xmp_datum_free(Exiv2XmpDatum *datum);
 Exiv2XmpDatum* exiv2_xmp_data_find_key(const Exiv2XmpData *data, const char *key, Exiv2Error **error);
+Exiv2XmpDatumIterator* exiv2_xmp_data_iterator(const Exiv2XmpData *data);
+int exiv2_xmp_data_iterator_has_next(const Exiv2XmpDatumIterator *iter);
+Exiv2XmpDatum* exiv2_xmp_datum_iterator_next(Exiv2XmpDatumIterator *iter);
+int exiv2_xmp_datum_type_id(const Exiv2XmpDatum *datum);
+const char* exiv2_xmp_datum_type_name(const Exiv2XmpDatum *datum);
+long exiv2_xmp_datum_count(const Exiv2XmpDatum *datum);
+long exiv2_xmp_datum_size(const Exiv2XmpDatum *datum);
+long exiv2_xmp_datum_to_long(const Exiv2XmpDatum *datum, long n, int *ok);
+double exiv2_xmp_datum_to_double(const Exiv2XmpDatum *datum, long n, int *ok);
+int exiv2_xmp_datum_to_rational(const Exiv2XmpDatum *datum, long n, long long *numerator, long long *denominator);
+unsigned char* exiv2_xmp_datum_to_bytes(const Exiv2XmpDatum *datum, long *size);
 
 Exiv2IptcData* exiv2_image_get_iptc_data(const Exiv2Image *img);
 void exiv2_iptc_data_free(Exiv2IptcData *data);
 const char* exiv2_iptc_datum_key(const Exiv2IptcDatum *datum);
 const char* exiv2_iptc_datum_to_string(const Exiv2IptcDatum *datum);
+char* exiv2_iptc_datum_print(const Exiv2IptcDatum *datum);
 void exiv2_iptc_datum_free(Exiv2IptcDatum *datum);
 Exiv2IptcDatum* exiv2_iptc_data_find_key(const Exiv2IptcData *data, const char *key, Exiv2Error **error);
+char* exiv2_iptc_key_normalize(const char *key, Exiv2Error **error);
 Exiv2IptcDatumIterator* exiv2_iptc_data_iterator(const Exiv2IptcData *data);
 int exiv2_iptc_data_iterator_has_next(const Exiv2IptcDatumIterator *iter);
 Exiv2IptcDatum* exiv2_iptc_datum_iterator_next(Exiv2IptcDatumIterator *iter);
+int exiv2_iptc_datum_type_id(const Exiv2IptcDatum *datum);
+const char* exiv2_iptc_datum_type_name(const Exiv2IptcDatum *datum);
+long exiv2_iptc_datum_count(const Exiv2IptcDatum *datum);
+long exiv2_iptc_datum_size(const Exiv2IptcDatum *datum);
+long exiv2_iptc_datum_to_long(const Exiv2IptcDatum *datum, long n, int *ok);
+double exiv2_iptc_datum_to_double(const Exiv2IptcDatum *datum, long n, int *ok);
+int exiv2_iptc_datum_to_rational(const Exiv2IptcDatum *datum, long n, long long *numerator, long long *denominator);
+unsigned char* exiv2_iptc_datum_to_bytes(const Exiv2IptcDatum *datum, long *size);
 
 Exiv2ExifData* exiv2_image_get_exif_data(const Exiv2Image *img);
 const char* exiv2_exif_datum_key(const Exiv2ExifDatum *datum);
 const char* exiv2_exif_datum_to_string(const Exiv2ExifDatum *datum);
+char* exiv2_exif_datum_print(const Exiv2ExifDatum *datum, const Exiv2ExifData *data);
 void exiv2_exif_datum_free(Exiv2ExifDatum *datum);
 void exiv2_exif_data_free(Exiv2ExifData *data);
 Exiv2ExifDatum* exiv2_exif_data_find_key(const Exiv2ExifData *data, const char *key, Exiv2Error **error);
 Exiv2ExifDatumIterator* exiv2_exif_data_iterator(const Exiv2ExifData *data);
 int exiv2_exif_data_iterator_has_next(const Exiv2ExifDatumIterator *iter);
 Exiv2ExifDatum* exiv2_exif_datum_iterator_next(Exiv2ExifDatumIterator *iter);
+int exiv2_exif_datum_type_id(const Exiv2ExifDatum *datum);
+const char* exiv2_exif_datum_type_name(const Exiv2ExifDatum *datum);
+long exiv2_exif_datum_count(const Exiv2ExifDatum *datum);
+long exiv2_exif_datum_size(const Exiv2ExifDatum *datum);
+long exiv2_exif_datum_to_long(const Exiv2ExifDatum *datum, long n, int *ok);
+double exiv2_exif_datum_to_double(const Exiv2ExifDatum *datum, long n, int *ok);
+int exiv2_exif_datum_to_rational(const Exiv2ExifDatum *datum, long n, long long *numerator, long long *denominator);
+unsigned char* exiv2_exif_datum_to_bytes(const Exiv2ExifDatum *datum, long *size);
 
 void exiv2_exif_strip_key(Exiv2Image *img, char *key, Exiv2Error **error);
 void exiv2_iptc_strip_key(Exiv2Image *img, char *key, Exiv2Error **error);
@@ -68,7 +151,56 @@ void exiv2_xmp_strip_key(Exiv2Image *img, char *key, Exiv2Error **error);
 const unsigned char* exiv2_image_icc_profile(Exiv2Image *img);
 long exiv2_image_icc_profile_size(Exiv2Image *img);
 
+unsigned char* exiv2_image_exif_thumbnail(const Exiv2Image *img, long *size, const char **mime_type, Exiv2Error **error);
+
+Exiv2PreviewList* exiv2_image_get_previews(const Exiv2Image *img, Exiv2Error **error);
+int exiv2_preview_list_count(const Exiv2PreviewList *list);
+const char* exiv2_preview_list_mime_type(const Exiv2PreviewList *list, int n);
+const char* exiv2_preview_list_extension(const Exiv2PreviewList *list, int n);
+long exiv2_preview_list_width(const Exiv2PreviewList *list, int n);
+long exiv2_preview_list_height(const Exiv2PreviewList *list, int n);
+long exiv2_preview_list_size(const Exiv2PreviewList *list, int n);
+void exiv2_preview_list_free(Exiv2PreviewList *list);
+
+Exiv2TagList* exiv2_lookup_tag(const char *key, Exiv2Error **error);
+Exiv2TagList* exiv2_list_tags(const char *group, Exiv2Error **error);
+int exiv2_tag_list_count(const Exiv2TagList *list);
+const char* exiv2_tag_list_key(const Exiv2TagList *list, int n);
+int exiv2_tag_list_id(const Exiv2TagList *list, int n);
+const char* exiv2_tag_list_group(const Exiv2TagList *list, int n);
+const char* exiv2_tag_list_name(const Exiv2TagList *list, int n);
+const char* exiv2_tag_list_label(const Exiv2TagList *list, int n);
+const char* exiv2_tag_list_description(const Exiv2TagList *list, int n);
+int exiv2_tag_list_type_id(const Exiv2TagList *list, int n);
+int exiv2_tag_list_value_count(const Exiv2TagList *list, int n);
+void exiv2_tag_list_free(Exiv2TagList *list);
+unsigned char* exiv2_image_get_preview_bytes(const Exiv2Image *img, int n, long *size, int *found, Exiv2Error **error);
+
+Exiv2MetadataTx* exiv2_image_edit(Exiv2Image *img, Exiv2Error **error);
+void exiv2_metadata_tx_set_exif(Exiv2MetadataTx *tx, const char *key, int type, const char *value, Exiv2Error **error);
+void exiv2_metadata_tx_set_iptc(Exiv2MetadataTx *tx, const char *key, int type, const char *value, Exiv2Error **error);
+void exiv2_metadata_tx_add_iptc(Exiv2MetadataTx *tx, const char *key, int type, const char *value, Exiv2Error **error);
+void exiv2_metadata_tx_set_iptc_strings(Exiv2MetadataTx *tx, const char *key, const char **values, int count, Exiv2Error **error);
+void exiv2_metadata_tx_set_xmp(Exiv2MetadataTx *tx, const char *key, int type, const char *value, Exiv2Error **error);
+void exiv2_metadata_tx_set_xmp_array(Exiv2MetadataTx *tx, const char *key, int type, const char **values, int count, Exiv2Error **error);
+void exiv2_metadata_tx_set_xmp_lang_alt(Exiv2MetadataTx *tx, const char *key, const char **langs, const char **values, int count, Exiv2Error **error);
+void exiv2_metadata_tx_exif_strip_key(Exiv2MetadataTx *tx, const char *key, Exiv2Error **error);
+void exiv2_metadata_tx_iptc_strip_key(Exiv2MetadataTx *tx, const char *key, Exiv2Error **error);
+void exiv2_metadata_tx_xmp_strip_key(Exiv2MetadataTx *tx, const char *key, Exiv2Error **error);
+void exiv2_metadata_tx_set_exif_thumbnail(Exiv2MetadataTx *tx, const unsigned char *jpeg, long size, Exiv2Error **error);
+void exiv2_metadata_tx_erase_exif_thumbnail(Exiv2MetadataTx *tx, Exiv2Error **error);
+void exiv2_metadata_tx_copy_metadata(Exiv2MetadataTx *tx, const Exiv2Image *src, int exif, int iptc, int xmp, int icc_profile, int comment, Exiv2Error **error);
+void exiv2_metadata_tx_commit(Exiv2MetadataTx *tx, Exiv2Error **error);
+void exiv2_metadata_tx_free(Exiv2MetadataTx *tx);
+
 void exiv2_log_msg_set_level(const int level);
+void exiv2_log_msg_init();
+void exiv2_log_msg_set_go_handler(int enabled);
+
+// goexiv_log_message is implemented in Go. It passes a message of libexiv2 to the handler set with SetLogHandler.
+void goexiv_log_message(int level, char *msg);
+// goexiv_log_capture is implemented in Go. It appends a message to the list referenced by handle.
+void goexiv_log_capture(uintptr_t handle, int level, char *msg);
 
 int exiv2_error_code(const Exiv2Error *e);
 const char *exiv2_error_what(const Exiv2Error *e);
@@ -77,3 +209,5 @@ void exiv2_error_free(Exiv2Error *e);
 #ifdef __cplusplus
 } // extern "C"
 #endif
+
+#endif // GOEXIV_HELPER_H
@@ -56,8 +56,50 @@ func makeIptcDatum(data *IptcData, cdatum *C.Exiv2IptcDatum) *IptcDatum {
 	return datum
 }
 
+// Close frees the underlying C structure. Datums and iterators obtained from
+// the data can't be used afterwards. It is safe to call Close more than once.
+func (d *IptcData) Close() error {
+	if d.data == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(d, nil)
+	C.exiv2_iptc_data_free(d.data)
+	d.data = nil
+
+	return nil
+}
+
+func (d *IptcData) isClosed() bool {
+	return d.data == nil || d.img.img == nil
+}
+
+// Close frees the underlying C structure. It is safe to call Close more than once.
+func (d *IptcDatum) Close() error {
+	if d.datum == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(d, nil)
+	C.exiv2_iptc_datum_free(d.datum)
+	d.datum = nil
+
+	return nil
+}
+
+func (d *IptcDatum) isClosed() bool {
+	return d.datum == nil || d.data.isClosed()
+}
+
 func (i *Image) GetIptcData() *IptcData {
-	return makeIptcData(i, C.exiv2_image_get_iptc_data(i.img))
+	if i.img == nil {
+		return &IptcData{img: i}
+	}
+
+	data := makeIptcData(i, C.exiv2_image_get_iptc_data(i.img))
+	runtime.KeepAlive(i)
+
+	return data
 }
 
 func (i *Image) SetIptcString(key, value string) error {
@@ -68,6 +110,20 @@ func (i *Image) SetIptcShort(key, value string) error {
 	return i.SetMetadataShort("iptc", key, value)
 }
 
+// AddIptcString adds one more value to a repeatable iptc dataset, e.g. Iptc.Application2.Keywords
+func (i *Image) AddIptcString(key, value string) error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.AddIptcString(key, value)
+	})
+}
+
+// SetIptcStrings replaces all values of a repeatable iptc dataset, e.g. Iptc.Application2.Keywords
+func (i *Image) SetIptcStrings(key string, values []string) error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.SetIptcStrings(key, values)
+	})
+}
+
 func (d *IptcData) GetString(key string) (string, error) {
 	datum, err := d.FindKey(key)
 	if err != nil {
@@ -82,6 +138,10 @@ func (d *IptcData) GetString(key string) (string, error) {
 }
 
 func (d *IptcData) FindKey(key string) (*IptcDatum, error) {
+	if d.isClosed() {
+		return nil, ErrClosed
+	}
+
 	ckey := C.CString(key)
 	defer C.free(unsafe.Pointer(ckey))
 
@@ -90,7 +150,7 @@ func (d *IptcData) FindKey(key string) (*IptcDatum, error) {
 	cdatum := C.exiv2_iptc_data_find_key(d.data, ckey, &cerr)
 
 	if cerr != nil {
-		err := makeError(cerr)
+		err := makeError(cerr, "FindKey", key)
 		C.exiv2_error_free(cerr)
 		return nil, err
 	}
@@ -98,24 +158,276 @@ func (d *IptcData) FindKey(key string) (*IptcDatum, error) {
 	return makeIptcDatum(d, cdatum), nil
 }
 
+// FindAll returns every datum of the specified key in the order they are stored,
+// e.g. all Iptc.Application2.Keywords entries. It returns an error if the key is invalid.
+func (d *IptcData) FindAll(key string) ([]*IptcDatum, error) {
+	if d.isClosed() {
+		return nil, ErrClosed
+	}
+
+	canonicalKey, err := normalizeIptcKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*IptcDatum
+
+	iter := d.Iterator()
+	defer iter.Close()
+
+	for iter.HasNext() {
+		datum := iter.Next()
+		if datum == nil {
+			break
+		}
+		if datum.Key() == canonicalKey {
+			result = append(result, datum)
+		} else {
+			datum.Close()
+		}
+	}
+
+	return result, nil
+}
+
+// normalizeIptcKey returns the canonical form of the key, e.g. Iptc.Application2.Keywords for Iptc.0x0002.0x0019
+func normalizeIptcKey(key string) (string, error) {
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+
+	var cerr *C.Exiv2Error
+
+	cstr := C.exiv2_iptc_key_normalize(ckey, &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "FindKey", key)
+		C.exiv2_error_free(cerr)
+		return "", err
+	}
+
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr), nil
+}
+
 // Key returns the IPTC key of the datum.
 func (d *IptcDatum) Key() string {
-	return C.GoString(C.exiv2_iptc_datum_key(d.datum))
+	if d.isClosed() {
+		return ""
+	}
+
+	cstr := C.exiv2_iptc_datum_key(d.datum)
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
 }
 
 func (d *IptcDatum) String() string {
+	if d.isClosed() {
+		return ""
+	}
+
 	cstr := C.exiv2_iptc_datum_to_string(d.datum)
 	defer C.free(unsafe.Pointer(cstr))
 
 	return C.GoString(cstr)
 }
 
+// Interpreted returns the value as printed by exiv2 for humans. For most IPTC datasets it is the same as String.
+func (d *IptcDatum) Interpreted() string {
+	if d.isClosed() {
+		return ""
+	}
+
+	cstr := C.exiv2_iptc_datum_print(d.datum)
+	runtime.KeepAlive(d)
+
+	if cstr == nil {
+		return d.String()
+	}
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
+
+// TypeID returns the type of the datum value.
+func (d *IptcDatum) TypeID() TypeID {
+	if d.isClosed() {
+		return TypeInvalid
+	}
+
+	result := TypeID(C.exiv2_iptc_datum_type_id(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// TypeName returns the name of the datum value type, e.g. "Rational".
+func (d *IptcDatum) TypeName() string {
+	if d.isClosed() {
+		return ""
+	}
+
+	result := C.GoString(C.exiv2_iptc_datum_type_name(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Count returns the number of components of the datum value.
+func (d *IptcDatum) Count() int64 {
+	if d.isClosed() {
+		return 0
+	}
+
+	result := int64(C.exiv2_iptc_datum_count(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Size returns the size of the datum value in bytes.
+func (d *IptcDatum) Size() int64 {
+	if d.isClosed() {
+		return 0
+	}
+
+	result := int64(C.exiv2_iptc_datum_size(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Int64 returns the n-th component of the datum value converted to an integer.
+func (d *IptcDatum) Int64(n int) (int64, error) {
+	if d.isClosed() {
+		return 0, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return 0, err
+	}
+
+	var ok C.int
+	result := int64(C.exiv2_iptc_datum_to_long(d.datum, C.long(n), &ok))
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return 0, ErrValueConversion
+	}
+
+	return result, nil
+}
+
+// Float64 returns the n-th component of the datum value converted to a float.
+func (d *IptcDatum) Float64(n int) (float64, error) {
+	if d.isClosed() {
+		return 0, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return 0, err
+	}
+
+	var ok C.int
+	result := float64(C.exiv2_iptc_datum_to_double(d.datum, C.long(n), &ok))
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return 0, ErrValueConversion
+	}
+
+	return result, nil
+}
+
+// Rational returns the n-th component of the datum value converted to a fraction.
+func (d *IptcDatum) Rational(n int) (Rational, error) {
+	if d.isClosed() {
+		return Rational{}, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return Rational{}, err
+	}
+
+	var num, den C.longlong
+	ok := C.exiv2_iptc_datum_to_rational(d.datum, C.long(n), &num, &den)
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return Rational{}, ErrValueConversion
+	}
+
+	return Rational{int64(num), int64(den)}, nil
+}
+
+// Bytes returns the raw datum value. Multi-byte numbers are encoded in little-endian order.
+func (d *IptcDatum) Bytes() []byte {
+	if d.isClosed() {
+		return nil
+	}
+
+	var size C.long
+	ptr := C.exiv2_iptc_datum_to_bytes(d.datum, &size)
+	runtime.KeepAlive(d)
+
+	if ptr == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(ptr))
+
+	return C.GoBytes(unsafe.Pointer(ptr), C.int(size))
+}
+
 // Returns all IPTC tags
 func (d *IptcData) AllTags() map[string]string {
 	keyValues := map[string]string{}
-	for i := d.Iterator(); i.HasNext(); {
-		d := i.Next()
-		keyValues[d.Key()] = d.String()
+	iter := d.Iterator()
+	defer iter.Close()
+
+	for iter.HasNext() {
+		datum := iter.Next()
+		if datum == nil {
+			break
+		}
+		keyValues[datum.Key()] = datum.String()
+		datum.Close()
+	}
+
+	return keyValues
+}
+
+// AllTagsInterpreted returns all IPTC tags with their human-readable values, see IptcDatum.Interpreted
+func (d *IptcData) AllTagsInterpreted() map[string]string {
+	keyValues := map[string]string{}
+	iter := d.Iterator()
+	defer iter.Close()
+
+	for iter.HasNext() {
+		datum := iter.Next()
+		if datum == nil {
+			break
+		}
+		keyValues[datum.Key()] = datum.Interpreted()
+		datum.Close()
+	}
+
+	return keyValues
+}
+
+// AllTagsMulti returns all IPTC tags. Unlike AllTags, it keeps every value of repeated datasets.
+func (d *IptcData) AllTagsMulti() map[string][]string {
+	keyValues := map[string][]string{}
+	iter := d.Iterator()
+	defer iter.Close()
+
+	for iter.HasNext() {
+		datum := iter.Next()
+		if datum == nil {
+			break
+		}
+		keyValues[datum.Key()] = append(keyValues[datum.Key()], datum.String())
+		datum.Close()
 	}
 
 	return keyValues
@@ -123,16 +435,28 @@ func (d *IptcData) AllTags() map[string]string {
 
 // Iterator returns a new IptcDatumIterator to iterate over all IPTC data.
 func (d *IptcData) Iterator() *IptcDatumIterator {
+	if d.isClosed() {
+		return &IptcDatumIterator{data: d}
+	}
+
 	return makeIptcDatumIterator(d, C.exiv2_iptc_data_iterator(d.data))
 }
 
 // HasNext returns true as long as the iterator has another datum to deliver.
 func (i *IptcDatumIterator) HasNext() bool {
+	if i.isClosed() {
+		return false
+	}
+
 	return C.exiv2_iptc_data_iterator_has_next(i.iter) != 0
 }
 
 // Next returns the next IptcDatum of the iterator or nil if iterator has reached the end.
 func (i *IptcDatumIterator) Next() *IptcDatum {
+	if i.isClosed() {
+		return nil
+	}
+
 	return makeIptcDatum(i.data, C.exiv2_iptc_datum_iterator_next(i.iter))
 }
 
@@ -146,6 +470,23 @@ func makeIptcDatumIterator(data *IptcData, cIter *C.Exiv2IptcDatumIterator) *Ipt
 	return datum
 }
 
+// Close frees the underlying C structure. It is safe to call Close more than once.
+func (i *IptcDatumIterator) Close() error {
+	if i.iter == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(i, nil)
+	C.exiv2_iptc_datum_iterator_free(i.iter)
+	i.iter = nil
+
+	return nil
+}
+
+func (i *IptcDatumIterator) isClosed() bool {
+	return i.iter == nil || i.data.isClosed()
+}
+
 func (i *Image) IptcStripKey(key string) error {
 	return i.StripKey(IPTC, key)
 }
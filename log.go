@@ -0,0 +1,80 @@
+package goexiv
+
+// #cgo pkg-config: exiv2
+// #include "helper.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"runtime/cgo"
+	"strings"
+	"sync"
+)
+
+// LogHandler receives the messages logged by libexiv2
+type LogHandler func(level LogMsgLevel, msg string)
+
+// LogMessage is a message logged by libexiv2
+type LogMessage struct {
+	Level   LogMsgLevel
+	Message string
+}
+
+var (
+	logHandlerMu sync.RWMutex
+	logHandler   LogHandler
+)
+
+func init() {
+	C.exiv2_log_msg_init()
+}
+
+// SetLogHandler routes the messages of libexiv2 to h instead of stderr.
+// Passing nil restores the output to stderr.
+// Messages below the level set with SetLogMsgLevel are not logged at all.
+//
+// The handler may be called concurrently from any goroutine using goexiv and must not block.
+func SetLogHandler(h LogHandler) {
+	logHandlerMu.Lock()
+	defer logHandlerMu.Unlock()
+
+	logHandler = h
+
+	enabled := 0
+	if h != nil {
+		enabled = 1
+	}
+
+	C.exiv2_log_msg_set_go_handler(C.int(enabled))
+}
+
+// Warnings returns the warnings and errors logged by libexiv2 during the last ReadMetadata call.
+// Metadata which has been read with warnings is usually partially corrupted.
+// Nothing is captured if the log level set with SetLogMsgLevel is above LogMsgError.
+func (i *Image) Warnings() []LogMessage {
+	return i.warnings
+}
+
+func logMessageText(msg *C.char) string {
+	return strings.TrimRight(C.GoString(msg), "\n")
+}
+
+//export goexiv_log_message
+func goexiv_log_message(level C.int, msg *C.char) {
+	logHandlerMu.RLock()
+	h := logHandler
+	logHandlerMu.RUnlock()
+
+	if h != nil {
+		h(LogMsgLevel(level), logMessageText(msg))
+	}
+}
+
+//export goexiv_log_capture
+func goexiv_log_capture(handle C.uintptr_t, level C.int, msg *C.char) {
+	messages := cgo.Handle(handle).Value().(*[]LogMessage)
+	*messages = append(*messages, LogMessage{
+		Level:   LogMsgLevel(level),
+		Message: logMessageText(msg),
+	})
+}
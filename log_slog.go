@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package goexiv
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler returns a LogHandler which passes the messages of libexiv2 to h:
+//
+//	goexiv.SetLogHandler(goexiv.SlogHandler(slog.Default().Handler()))
+func SlogHandler(h slog.Handler) LogHandler {
+	logger := slog.New(h)
+
+	return func(level LogMsgLevel, msg string) {
+		logger.Log(context.Background(), slogLevel(level), msg)
+	}
+}
+
+func slogLevel(level LogMsgLevel) slog.Level {
+	switch level {
+	case LogMsgDebug:
+		return slog.LevelDebug
+	case LogMsgInfo:
+		return slog.LevelInfo
+	case LogMsgWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
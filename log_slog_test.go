@@ -0,0 +1,29 @@
+//go:build go1.21
+
+package goexiv_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/kolesa-team/goexiv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	goexiv.SetLogHandler(goexiv.SlogHandler(slog.NewTextHandler(&buf, nil)))
+	defer goexiv.SetLogHandler(nil)
+
+	img, err := goexiv.OpenBytes(corruptedTiff)
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.ReadMetadata())
+	require.NotEmpty(t, img.Warnings())
+
+	assert.Contains(t, buf.String(), img.Warnings()[0].Message)
+	assert.Regexp(t, "level=(WARN|ERROR)", buf.String())
+}
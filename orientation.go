@@ -0,0 +1,105 @@
+package goexiv
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Orientation is the value of Exif.Image.Orientation: the transformation
+// needed to display the stored pixels the right way up.
+type Orientation int
+
+const (
+	OrientationNormal         Orientation = 1
+	OrientationFlipHorizontal Orientation = 2
+	OrientationRotate180      Orientation = 3
+	OrientationFlipVertical   Orientation = 4
+	// OrientationTranspose is a flip across the top-left to bottom-right diagonal
+	OrientationTranspose Orientation = 5
+	// OrientationRotate90 needs a clockwise rotation by 90 degrees to be displayed
+	OrientationRotate90 Orientation = 6
+	// OrientationTransverse is a flip across the top-right to bottom-left diagonal
+	OrientationTransverse Orientation = 7
+	// OrientationRotate270 needs a clockwise rotation by 270 degrees to be displayed
+	OrientationRotate270 Orientation = 8
+)
+
+var ErrInvalidOrientation = errors.New("invalid orientation")
+
+// IsValid returns true for the orientations defined by the EXIF standard
+func (o Orientation) IsValid() bool {
+	return o >= OrientationNormal && o <= OrientationRotate270
+}
+
+// SwapsDimensions returns true if the width and height of the image are swapped when it is displayed
+func (o Orientation) SwapsDimensions() bool {
+	return o >= OrientationTranspose && o <= OrientationRotate270
+}
+
+// Orientation returns the orientation of the image from Exif.Image.Orientation,
+// or from Xmp.tiff.Orientation if there is no EXIF orientation.
+// OrientationNormal is returned if neither is set. ReadMetadata() must be called before.
+func (i *Image) Orientation() (Orientation, error) {
+	if err := i.checkImage(); err != nil {
+		return 0, err
+	}
+
+	datum, err := i.GetExifData().FindKey("Exif.Image.Orientation")
+	if err != nil {
+		return 0, err
+	}
+
+	if datum != nil {
+		defer datum.Close()
+
+		value, err := datum.Int64(0)
+		if err != nil || !Orientation(value).IsValid() {
+			return 0, ErrInvalidOrientation
+		}
+
+		return Orientation(value), nil
+	}
+
+	value, err := i.GetXmpData().GetString("Xmp.tiff.Orientation")
+	if err == ErrMetadataKeyNotFound {
+		return OrientationNormal, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || !Orientation(number).IsValid() {
+		return 0, ErrInvalidOrientation
+	}
+
+	return Orientation(number), nil
+}
+
+// SetOrientation writes Exif.Image.Orientation, along with Xmp.tiff.Orientation
+// if the image has one, with a single metadata write
+func (i *Image) SetOrientation(o Orientation) error {
+	if !o.IsValid() {
+		return ErrInvalidOrientation
+	}
+
+	xmpKeys, err := i.metadataKeys(XMP)
+	if err != nil {
+		return err
+	}
+
+	value := strconv.Itoa(int(o))
+
+	return i.edit(func(tx *MetadataTx) error {
+		if err := tx.SetExifShort("Exif.Image.Orientation", value); err != nil {
+			return err
+		}
+
+		for _, key := range xmpKeys {
+			if key == "Xmp.tiff.Orientation" {
+				return tx.SetXmpString(key, value)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+package goexiv
+
+// #cgo pkg-config: exiv2
+// #include "helper.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+)
+
+// Preview describes an image embedded in the metadata, e.g. a camera-made JPEG preview of a RAW file
+type Preview struct {
+	MimeType string
+	// Extension is the file extension of the preview format including the dot, e.g. ".jpg"
+	Extension string
+	Width     int64
+	Height    int64
+	// Size is the size of the preview in bytes
+	Size int64
+}
+
+var ErrPreviewNotFound = errors.New("preview not found")
+
+// Previews returns the embedded previews of the image, ordered by their size from the smallest.
+// ReadMetadata() must be called before.
+func (i *Image) Previews() ([]Preview, error) {
+	if err := i.checkImage(); err != nil {
+		return nil, err
+	}
+
+	var cerr *C.Exiv2Error
+
+	list := C.exiv2_image_get_previews(i.img, &cerr)
+	runtime.KeepAlive(i)
+
+	if cerr != nil {
+		err := makeError(cerr, "Previews", "")
+		C.exiv2_error_free(cerr)
+		return nil, err
+	}
+	defer C.exiv2_preview_list_free(list)
+
+	previews := make([]Preview, int(C.exiv2_preview_list_count(list)))
+	for n := range previews {
+		cn := C.int(n)
+		previews[n] = Preview{
+			MimeType:  C.GoString(C.exiv2_preview_list_mime_type(list, cn)),
+			Extension: C.GoString(C.exiv2_preview_list_extension(list, cn)),
+			Width:     int64(C.exiv2_preview_list_width(list, cn)),
+			Height:    int64(C.exiv2_preview_list_height(list, cn)),
+			Size:      int64(C.exiv2_preview_list_size(list, cn)),
+		}
+	}
+
+	return previews, nil
+}
+
+// PreviewBytes returns the preview with the given index in the list returned by Previews().
+// It returns ErrPreviewNotFound if there is no such preview.
+func (i *Image) PreviewBytes(idx int) ([]byte, error) {
+	if err := i.checkImage(); err != nil {
+		return nil, err
+	}
+
+	var size C.long
+	var found C.int
+	var cerr *C.Exiv2Error
+
+	ptr := C.exiv2_image_get_preview_bytes(i.img, C.int(idx), &size, &found, &cerr)
+	runtime.KeepAlive(i)
+
+	if cerr != nil {
+		err := makeError(cerr, "PreviewBytes", "")
+		C.exiv2_error_free(cerr)
+		return nil, err
+	}
+
+	if found == 0 {
+		return nil, ErrPreviewNotFound
+	}
+
+	if ptr == nil {
+		return []byte{}, nil
+	}
+	defer C.free(unsafe.Pointer(ptr))
+
+	return C.GoBytes(unsafe.Pointer(ptr), C.int(size)), nil
+}
@@ -0,0 +1,55 @@
+package goexiv
+
+// #cgo pkg-config: exiv2
+// #include "helper.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"io"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// openReaderAt opens an image which is read through r on demand.
+// The closer, if any, is closed along with the image. Errors are reported as op name.
+func openReaderAt(op, name string, r io.ReaderAt, size int64, closer io.Closer) (*Image, error) {
+	if size <= 0 {
+		return nil, newError(ErrorCodeEmptyInput, "input is empty", op, name)
+	}
+
+	if int64(C.long(size)) != size {
+		return nil, newError(ErrorCodeInputTooLarge, "input is too large", op, name)
+	}
+
+	handle := cgo.NewHandle(r)
+
+	var cerr *C.Exiv2Error
+
+	cimg := C.exiv2_image_factory_open_reader(C.uintptr_t(handle), C.long(size), &cerr)
+
+	if cerr != nil {
+		handle.Delete()
+		err := makeError(cerr, op, name)
+		C.exiv2_error_free(cerr)
+		return nil, err
+	}
+
+	img := makeImage(cimg, nil)
+	img.reader = handle
+	img.closer = closer
+
+	return img, nil
+}
+
+//export goexiv_reader_read_at
+func goexiv_reader_read_at(handle C.uintptr_t, buf *C.uchar, size C.long, offset C.long) C.long {
+	r := cgo.Handle(handle).Value().(io.ReaderAt)
+
+	n, err := r.ReadAt(unsafe.Slice((*byte)(unsafe.Pointer(buf)), int(size)), int64(offset))
+	if n < int(size) && err != nil && err != io.EOF {
+		return -1
+	}
+
+	return C.long(n)
+}
@@ -0,0 +1,110 @@
+package goexiv
+
+// #cgo pkg-config: exiv2
+// #include "helper.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+)
+
+// TagInfo describes a metadata tag known to libexiv2
+type TagInfo struct {
+	// Key is the canonical key of the tag, e.g. "Exif.Photo.ExposureTime"
+	Key string
+	// ID is the tag number of an EXIF tag or the dataset number of an IPTC tag. It is 0 for XMP properties.
+	ID uint16
+	// Group is the IFD of an EXIF tag (e.g. "Photo"), the record of an IPTC tag (e.g. "Application2")
+	// or the namespace prefix of an XMP property (e.g. "dc")
+	Group string
+	Name  string
+	// Label is a human-readable title of the tag, e.g. "Exposure Time"
+	Label       string
+	Description string
+	// Type is the default type of the tag value
+	Type TypeID
+	// Count is the default number of components of the tag value, or -1 if it is not fixed
+	Count int
+}
+
+// LookupTag returns the description of the tag with the given key, e.g. "Exif.Photo.ExposureTime".
+// Unknown keys return an error matching ErrInvalidKey.
+func LookupTag(key string) (TagInfo, error) {
+	return lookupTag("LookupTag", key)
+}
+
+func lookupTag(op, key string) (TagInfo, error) {
+	tags, err := tagList(op, key, func(ckey *C.char, cerr **C.Exiv2Error) *C.Exiv2TagList {
+		return C.exiv2_lookup_tag(ckey, cerr)
+	})
+	if err != nil {
+		return TagInfo{}, err
+	}
+
+	return tags[0], nil
+}
+
+// ListTags returns every tag known to libexiv2 in a group, which is given as a key prefix:
+// "Exif.Photo", "Iptc.Application2" or "Xmp.dc".
+func ListTags(group string) ([]TagInfo, error) {
+	return tagList("ListTags", group, func(cgroup *C.char, cerr **C.Exiv2Error) *C.Exiv2TagList {
+		return C.exiv2_list_tags(cgroup, cerr)
+	})
+}
+
+func tagList(op, arg string, fn func(*C.char, **C.Exiv2Error) *C.Exiv2TagList) ([]TagInfo, error) {
+	carg := C.CString(arg)
+	defer C.free(unsafe.Pointer(carg))
+
+	var cerr *C.Exiv2Error
+
+	list := fn(carg, &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, op, arg)
+		C.exiv2_error_free(cerr)
+		return nil, err
+	}
+	defer C.exiv2_tag_list_free(list)
+
+	tags := make([]TagInfo, int(C.exiv2_tag_list_count(list)))
+	for n := range tags {
+		cn := C.int(n)
+		tags[n] = TagInfo{
+			Key:         C.GoString(C.exiv2_tag_list_key(list, cn)),
+			ID:          uint16(C.exiv2_tag_list_id(list, cn)),
+			Group:       C.GoString(C.exiv2_tag_list_group(list, cn)),
+			Name:        C.GoString(C.exiv2_tag_list_name(list, cn)),
+			Label:       C.GoString(C.exiv2_tag_list_label(list, cn)),
+			Description: C.GoString(C.exiv2_tag_list_description(list, cn)),
+			Type:        TypeID(C.exiv2_tag_list_type_id(list, cn)),
+			Count:       int(C.exiv2_tag_list_value_count(list, cn)),
+		}
+	}
+
+	return tags, nil
+}
+
+var metadataFormatNames = map[MetadataFormat]string{
+	EXIF: "exif",
+	IPTC: "iptc",
+	XMP:  "xmp",
+}
+
+var metadataKeyPrefixes = map[string]string{
+	"exif": "Exif.",
+	"iptc": "Iptc.",
+	"xmp":  "Xmp.",
+}
+
+// validateKey checks that the key belongs to the metadata format ("exif", "iptc" or "xmp").
+// The rest of the key is validated by libexiv2 when it is set.
+func validateKey(op, format, key string) error {
+	if !strings.HasPrefix(key, metadataKeyPrefixes[format]) {
+		return newError(ErrorCodeInvalidKey, "the key does not belong to "+format+" metadata", op, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package goexiv
+
+// #cgo pkg-config: exiv2
+// #include "helper.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"unsafe"
+)
+
+var ErrInvalidThumbnail = errors.New("thumbnail is not a JPEG image")
+
+// ExifThumbnail returns the thumbnail stored in the IFD1 of EXIF along with its MIME type,
+// e.g. "image/jpeg". It returns nil and no error if the image has no thumbnail.
+// ReadMetadata() must be called before.
+func (i *Image) ExifThumbnail() ([]byte, string, error) {
+	if err := i.checkImage(); err != nil {
+		return nil, "", err
+	}
+
+	var size C.long
+	var mimeType *C.char
+	var cerr *C.Exiv2Error
+
+	ptr := C.exiv2_image_exif_thumbnail(i.img, &size, &mimeType, &cerr)
+	runtime.KeepAlive(i)
+
+	if cerr != nil {
+		err := makeError(cerr, "ExifThumbnail", "")
+		C.exiv2_error_free(cerr)
+		return nil, "", err
+	}
+
+	if ptr == nil {
+		return nil, "", nil
+	}
+	defer C.free(unsafe.Pointer(ptr))
+
+	return C.GoBytes(unsafe.Pointer(ptr), C.int(size)), C.GoString(mimeType), nil
+}
+
+// SetExifThumbnail replaces the EXIF thumbnail with a JPEG image, e.g. after the image has been cropped
+func (i *Image) SetExifThumbnail(jpeg []byte) error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.SetExifThumbnail(jpeg)
+	})
+}
+
+// EraseExifThumbnail removes the EXIF thumbnail, which may show the original content of an edited image
+func (i *Image) EraseExifThumbnail() error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.EraseExifThumbnail()
+	})
+}
+
+// SetExifThumbnail stages the replacement of the EXIF thumbnail with a JPEG image
+func (t *MetadataTx) SetExifThumbnail(jpeg []byte) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	if !bytes.HasPrefix(jpeg, []byte{0xff, 0xd8}) {
+		return ErrInvalidThumbnail
+	}
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_metadata_tx_set_exif_thumbnail(t.tx, (*C.uchar)(unsafe.Pointer(&jpeg[0])), C.long(len(jpeg)), &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "SetExifThumbnail", "")
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+// EraseExifThumbnail stages the removal of the EXIF thumbnail
+func (t *MetadataTx) EraseExifThumbnail() error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_metadata_tx_erase_exif_thumbnail(t.tx, &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "EraseExifThumbnail", "")
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
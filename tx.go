@@ -0,0 +1,421 @@
+package goexiv
+
+// #cgo pkg-config: exiv2
+// #include "helper.h"
+// #include <stdlib.h>
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+)
+
+// MetadataTx stages metadata changes of an Image in memory.
+// The changes are applied with a single metadata write on Commit().
+type MetadataTx struct {
+	img *Image // We point to img to keep it alive
+	tx  *C.Exiv2MetadataTx
+}
+
+var ErrTxDone = errors.New("metadata transaction has already been committed or rolled back")
+
+func makeMetadataTx(img *Image, ctx *C.Exiv2MetadataTx) *MetadataTx {
+	tx := &MetadataTx{
+		img: img,
+		tx:  ctx,
+	}
+
+	runtime.SetFinalizer(tx, func(x *MetadataTx) {
+		if x.tx != nil {
+			C.exiv2_metadata_tx_free(x.tx)
+		}
+	})
+
+	return tx
+}
+
+// Edit starts a metadata transaction. The transaction works on a copy of the
+// image metadata taken at this point: changes made to the image by other means
+// before Commit() are overwritten for the families modified in the transaction.
+func (i *Image) Edit() (*MetadataTx, error) {
+	if err := i.checkImage(); err != nil {
+		return nil, err
+	}
+
+	var cerr *C.Exiv2Error
+
+	tx := C.exiv2_image_edit(i.img, &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "Edit", "")
+		C.exiv2_error_free(cerr)
+		return nil, err
+	}
+
+	return makeMetadataTx(i, tx), nil
+}
+
+// edit applies the changes made by fn with a single metadata write
+func (i *Image) edit(fn func(tx *MetadataTx) error) error {
+	tx, err := i.Edit()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetExifString stages an exif key with a given string value
+func (t *MetadataTx) SetExifString(key, value string) error {
+	return t.set("SetExifString", EXIF, TypeAsciiString, key, value)
+}
+
+// SetExifShort stages an exif key with a given short value
+func (t *MetadataTx) SetExifShort(key, value string) error {
+	return t.set("SetExifShort", EXIF, TypeUnsignedShort, key, value)
+}
+
+// SetExifLong stages an exif key with a given long value
+func (t *MetadataTx) SetExifLong(key, value string) error {
+	return t.set("SetExifLong", EXIF, TypeUnsignedLong, key, value)
+}
+
+// SetIptcString stages an iptc key with a given string value
+func (t *MetadataTx) SetIptcString(key, value string) error {
+	return t.set("SetIptcString", IPTC, TypeString, key, value)
+}
+
+// SetIptcShort stages an iptc key with a given short value
+func (t *MetadataTx) SetIptcShort(key, value string) error {
+	return t.set("SetIptcShort", IPTC, TypeUnsignedShort, key, value)
+}
+
+// AddIptcString stages one more value of a repeatable iptc dataset, e.g. Iptc.Application2.Keywords.
+// The existing values of the dataset are kept.
+func (t *MetadataTx) AddIptcString(key, value string) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	if err := validateKey("AddIptcString", "iptc", key); err != nil {
+		return err
+	}
+
+	cKey := C.CString(key)
+	cValue := C.CString(value)
+
+	defer func() {
+		C.free(unsafe.Pointer(cKey))
+		C.free(unsafe.Pointer(cValue))
+	}()
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_metadata_tx_add_iptc(t.tx, cKey, C.int(TypeString), cValue, &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "AddIptcString", key)
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+// SetIptcStrings stages all values of a repeatable iptc dataset, e.g. Iptc.Application2.Keywords.
+// The existing values of the dataset are replaced; an empty slice removes the dataset.
+func (t *MetadataTx) SetIptcStrings(key string, values []string) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	if err := validateKey("SetIptcStrings", "iptc", key); err != nil {
+		return err
+	}
+
+	cKey := C.CString(key)
+	cValues := makeCStringArray(values)
+
+	defer func() {
+		C.free(unsafe.Pointer(cKey))
+		freeCStringArray(cValues, len(values))
+	}()
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_metadata_tx_set_iptc_strings(t.tx, cKey, cValues, C.int(len(values)), &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "SetIptcStrings", key)
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+// SetXmpString stages an xmp key with a given text value
+func (t *MetadataTx) SetXmpString(key, value string) error {
+	return t.set("SetXmpString", XMP, TypeXmpText, key, value)
+}
+
+// SetXmpBag stages an xmp key with an unordered array of values, e.g. Xmp.dc.subject
+func (t *MetadataTx) SetXmpBag(key string, values []string) error {
+	return t.setXmpArray("SetXmpBag", TypeXmpBag, key, values)
+}
+
+// SetXmpSeq stages an xmp key with an ordered array of values, e.g. Xmp.dc.creator
+func (t *MetadataTx) SetXmpSeq(key string, values []string) error {
+	return t.setXmpArray("SetXmpSeq", TypeXmpSeq, key, values)
+}
+
+// SetXmpLangAlt stages an xmp key with language alternatives, e.g. Xmp.dc.title.
+// The values are keyed by their xml:lang qualifier; "x-default" is the default language.
+func (t *MetadataTx) SetXmpLangAlt(key string, values map[string]string) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	if err := validateKey("SetXmpLangAlt", "xmp", key); err != nil {
+		return err
+	}
+
+	langs := make([]string, 0, len(values))
+	texts := make([]string, 0, len(values))
+	for lang, text := range values {
+		langs = append(langs, lang)
+		texts = append(texts, text)
+	}
+
+	cKey := C.CString(key)
+	cLangs := makeCStringArray(langs)
+	cTexts := makeCStringArray(texts)
+
+	defer func() {
+		C.free(unsafe.Pointer(cKey))
+		freeCStringArray(cLangs, len(langs))
+		freeCStringArray(cTexts, len(texts))
+	}()
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_metadata_tx_set_xmp_lang_alt(t.tx, cKey, cLangs, cTexts, C.int(len(values)), &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, "SetXmpLangAlt", key)
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+func (t *MetadataTx) setXmpArray(op string, typeID TypeID, key string, values []string) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	if err := validateKey(op, "xmp", key); err != nil {
+		return err
+	}
+
+	cKey := C.CString(key)
+	cValues := makeCStringArray(values)
+
+	defer func() {
+		C.free(unsafe.Pointer(cKey))
+		freeCStringArray(cValues, len(values))
+	}()
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_metadata_tx_set_xmp_array(t.tx, cKey, C.int(typeID), cValues, C.int(len(values)), &cerr)
+
+	if cerr != nil {
+		err := makeError(cerr, op, key)
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+func (t *MetadataTx) set(op string, f MetadataFormat, typeID TypeID, key, value string) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	name, ok := metadataFormatNames[f]
+	if !ok {
+		return newError(ErrorCodeInvalidMetadataFormat, "invalid metadata format", op, key)
+	}
+
+	if err := validateKey(op, name, key); err != nil {
+		return err
+	}
+
+	cKey := C.CString(key)
+	cValue := C.CString(value)
+
+	defer func() {
+		C.free(unsafe.Pointer(cKey))
+		C.free(unsafe.Pointer(cValue))
+	}()
+
+	var cerr *C.Exiv2Error
+
+	switch f {
+	case EXIF:
+		C.exiv2_metadata_tx_set_exif(t.tx, cKey, C.int(typeID), cValue, &cerr)
+	case IPTC:
+		C.exiv2_metadata_tx_set_iptc(t.tx, cKey, C.int(typeID), cValue, &cerr)
+	case XMP:
+		C.exiv2_metadata_tx_set_xmp(t.tx, cKey, C.int(typeID), cValue, &cerr)
+	}
+
+	if cerr != nil {
+		err := makeError(cerr, op, key)
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+// typedValue is a value of a metadata key along with the type to store it with
+type typedValue struct {
+	typeID TypeID
+	key    string
+	value  string
+}
+
+func (t *MetadataTx) setValues(op string, f MetadataFormat, values []typedValue) error {
+	for _, v := range values {
+		if err := t.set(op, f, v.typeID, v.key, v.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StripKey stages the removal of every occurrence of a key
+func (t *MetadataTx) StripKey(f MetadataFormat, key string) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+
+	var cErr *C.Exiv2Error
+
+	switch f {
+	case EXIF:
+		C.exiv2_metadata_tx_exif_strip_key(t.tx, ckey, &cErr)
+	case IPTC:
+		C.exiv2_metadata_tx_iptc_strip_key(t.tx, ckey, &cErr)
+	case XMP:
+		C.exiv2_metadata_tx_xmp_strip_key(t.tx, ckey, &cErr)
+	default:
+		return newError(ErrorCodeInvalidMetadataFormat, "invalid metadata format", "StripKey", key)
+	}
+
+	if cErr != nil {
+		err := makeError(cErr, "StripKey", key)
+		C.exiv2_error_free(cErr)
+		return err
+	}
+
+	return nil
+}
+
+// stripKeys stages the removal of the keys for which match returns true
+func (t *MetadataTx) stripKeys(f MetadataFormat, keys []string, match func(key string) bool) error {
+	for _, key := range keys {
+		if !match(key) {
+			continue
+		}
+
+		if err := t.StripKey(f, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Commit applies all staged changes to the image and writes the metadata once.
+// The transaction cannot be used after Commit() returns, even if it fails.
+// If the write fails, the image keeps the metadata it had before Commit().
+func (t *MetadataTx) Commit() error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	if err := t.img.checkImage(); err != nil {
+		t.free()
+		return err
+	}
+
+	var cerr *C.Exiv2Error
+
+	C.exiv2_metadata_tx_commit(t.tx, &cerr)
+	t.free()
+
+	if cerr != nil {
+		err := makeError(cerr, "Commit", "")
+		C.exiv2_error_free(cerr)
+		return err
+	}
+
+	return nil
+}
+
+// Rollback discards all staged changes. Calling Rollback() after Commit() is a no-op,
+// so it can be deferred right after Edit().
+func (t *MetadataTx) Rollback() {
+	if t.tx == nil {
+		return
+	}
+
+	t.free()
+}
+
+func (t *MetadataTx) free() {
+	C.exiv2_metadata_tx_free(t.tx)
+	t.tx = nil
+	runtime.KeepAlive(t.img)
+}
+
+// makeCStringArray copies the strings to a C array, which must be released with freeCStringArray
+func makeCStringArray(values []string) **C.char {
+	if len(values) == 0 {
+		return nil
+	}
+
+	ptr := (**C.char)(C.malloc(C.size_t(len(values)) * C.size_t(unsafe.Sizeof((*C.char)(nil)))))
+	array := unsafe.Slice(ptr, len(values))
+	for i, value := range values {
+		array[i] = C.CString(value)
+	}
+
+	return ptr
+}
+
+func freeCStringArray(ptr **C.char, length int) {
+	if ptr == nil {
+		return
+	}
+
+	for _, value := range unsafe.Slice(ptr, length) {
+		C.free(unsafe.Pointer(value))
+	}
+	C.free(unsafe.Pointer(ptr))
+}
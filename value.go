@@ -0,0 +1,61 @@
+package goexiv
+
+import "errors"
+
+// TypeID identifies the type of a metadatum value. The constants mirror
+// the Exiv2::TypeId enumeration.
+type TypeID int
+
+const (
+	TypeUnsignedByte     TypeID = 1
+	TypeAsciiString      TypeID = 2
+	TypeUnsignedShort    TypeID = 3
+	TypeUnsignedLong     TypeID = 4
+	TypeUnsignedRational TypeID = 5
+	TypeSignedByte       TypeID = 6
+	TypeUndefined        TypeID = 7
+	TypeSignedShort      TypeID = 8
+	TypeSignedLong       TypeID = 9
+	TypeSignedRational   TypeID = 10
+	TypeTiffFloat        TypeID = 11
+	TypeTiffDouble       TypeID = 12
+	TypeTiffIfd          TypeID = 13
+	TypeUnsignedLongLong TypeID = 16
+	TypeSignedLongLong   TypeID = 17
+	TypeTiffIfd8         TypeID = 18
+	TypeString           TypeID = 0x10000
+	TypeDate             TypeID = 0x10001
+	TypeTime             TypeID = 0x10002
+	TypeComment          TypeID = 0x10003
+	TypeDirectory        TypeID = 0x10004
+	TypeXmpText          TypeID = 0x10005
+	TypeXmpAlt           TypeID = 0x10006
+	TypeXmpBag           TypeID = 0x10007
+	TypeXmpSeq           TypeID = 0x10008
+	TypeLangAlt          TypeID = 0x10009
+	TypeInvalid          TypeID = 0x1fffe
+)
+
+// Rational is a fraction as stored in rational EXIF values.
+type Rational struct {
+	Num int64
+	Den int64
+}
+
+var (
+	ErrValueIndexOutOfRange = errors.New("value component index out of range")
+	ErrValueConversion      = errors.New("value cannot be converted to the requested type")
+)
+
+// Float64 returns the value of the fraction. A zero denominator yields +Inf, -Inf or NaN.
+func (r Rational) Float64() float64 {
+	return float64(r.Num) / float64(r.Den)
+}
+
+func checkValueIndex(n int, count int64) error {
+	if n < 0 || int64(n) >= count {
+		return ErrValueIndexOutOfRange
+	}
+
+	return nil
+}
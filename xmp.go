@@ -22,6 +22,12 @@ type XmpDatum struct {
 	datum *C.Exiv2XmpDatum
 }
 
+// XmpDatumIterator wraps the respective C++ structure.
+type XmpDatumIterator struct {
+	data *XmpData
+	iter *C.Exiv2XmpDatumIterator
+}
+
 func makeXmpData(img *Image, cdata *C.Exiv2XmpData) *XmpData {
 	data := &XmpData{
 		img,
@@ -52,15 +58,105 @@ func makeXmpDatum(data *XmpData, cdatum *C.Exiv2XmpDatum) *XmpDatum {
 	return datum
 }
 
+// Close frees the underlying C structure. Datums and iterators obtained from
+// the data can't be used afterwards. It is safe to call Close more than once.
+func (d *XmpData) Close() error {
+	if d.data == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(d, nil)
+	C.exiv2_xmp_data_free(d.data)
+	d.data = nil
+
+	return nil
+}
+
+func (d *XmpData) isClosed() bool {
+	return d.data == nil || d.img.img == nil
+}
+
+// Close frees the underlying C structure. It is safe to call Close more than once.
+func (d *XmpDatum) Close() error {
+	if d.datum == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(d, nil)
+	C.exiv2_xmp_datum_free(d.datum)
+	d.datum = nil
+
+	return nil
+}
+
+func (d *XmpDatum) isClosed() bool {
+	return d.datum == nil || d.data.isClosed()
+}
+
 // GetXmpData returns the XmpData of an Image.
 func (i *Image) GetXmpData() *XmpData {
-	return makeXmpData(i, C.exiv2_image_get_xmp_data(i.img))
+	if i.img == nil {
+		return &XmpData{img: i}
+	}
+
+	data := makeXmpData(i, C.exiv2_image_get_xmp_data(i.img))
+	runtime.KeepAlive(i)
+
+	return data
+}
+
+// SetXmpString sets an xmp key with a given text value
+func (i *Image) SetXmpString(key, value string) error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.SetXmpString(key, value)
+	})
+}
+
+// SetXmpBag sets an xmp key with an unordered array of values, e.g. Xmp.dc.subject
+func (i *Image) SetXmpBag(key string, values []string) error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.SetXmpBag(key, values)
+	})
+}
+
+// SetXmpSeq sets an xmp key with an ordered array of values, e.g. Xmp.dc.creator
+func (i *Image) SetXmpSeq(key string, values []string) error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.SetXmpSeq(key, values)
+	})
+}
+
+// SetXmpLangAlt sets an xmp key with language alternatives, e.g. Xmp.dc.title.
+// The values are keyed by their xml:lang qualifier; "x-default" is the default language.
+func (i *Image) SetXmpLangAlt(key string, values map[string]string) error {
+	return i.edit(func(tx *MetadataTx) error {
+		return tx.SetXmpLangAlt(key, values)
+	})
+}
+
+// GetString returns the string value of the specified key.
+// It returns ErrMetadataKeyNotFound if the key is not found.
+func (d *XmpData) GetString(key string) (string, error) {
+	datum, err := d.FindKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	if datum == nil {
+		return "", ErrMetadataKeyNotFound
+	}
+
+	return datum.String(), nil
 }
 
 // FindKey tries to find the specified key and returns its data.
 // It returns an error if the key is invalid. If the key is not found, a
 // nil pointer will be returned
 func (d *XmpData) FindKey(key string) (*XmpDatum, error) {
+	if d.isClosed() {
+		return nil, ErrClosed
+	}
+
 	ckey := C.CString(key)
 	defer C.free(unsafe.Pointer(ckey))
 
@@ -69,7 +165,7 @@ func (d *XmpData) FindKey(key string) (*XmpDatum, error) {
 	cdatum := C.exiv2_xmp_data_find_key(d.data, ckey, &cerr)
 
 	if cerr != nil {
-		err := makeError(cerr)
+		err := makeError(cerr, "FindKey", key)
 		C.exiv2_error_free(cerr)
 		return nil, err
 	}
@@ -78,13 +174,266 @@ func (d *XmpData) FindKey(key string) (*XmpDatum, error) {
 	return makeXmpDatum(d, cdatum), nil
 }
 
+// Key returns the XMP key of the datum.
+func (d *XmpDatum) Key() string {
+	if d.isClosed() {
+		return ""
+	}
+
+	cstr := C.exiv2_xmp_datum_key(d.datum)
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
+
 func (d *XmpDatum) String() string {
+	if d.isClosed() {
+		return ""
+	}
+
 	cstr := C.exiv2_xmp_datum_to_string(d.datum)
 	defer C.free(unsafe.Pointer(cstr))
 
 	return C.GoString(cstr)
 }
 
+// Interpreted returns the value as printed by exiv2 for humans, e.g. "Normal program" for Xmp.exif.ExposureProgram,
+// whereas String returns the raw "2".
+func (d *XmpDatum) Interpreted() string {
+	if d.isClosed() {
+		return ""
+	}
+
+	cstr := C.exiv2_xmp_datum_print(d.datum)
+	runtime.KeepAlive(d)
+
+	if cstr == nil {
+		return d.String()
+	}
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
+
+// TypeID returns the type of the datum value.
+func (d *XmpDatum) TypeID() TypeID {
+	if d.isClosed() {
+		return TypeInvalid
+	}
+
+	result := TypeID(C.exiv2_xmp_datum_type_id(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// TypeName returns the name of the datum value type, e.g. "Rational".
+func (d *XmpDatum) TypeName() string {
+	if d.isClosed() {
+		return ""
+	}
+
+	result := C.GoString(C.exiv2_xmp_datum_type_name(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Count returns the number of components of the datum value.
+func (d *XmpDatum) Count() int64 {
+	if d.isClosed() {
+		return 0
+	}
+
+	result := int64(C.exiv2_xmp_datum_count(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Size returns the size of the datum value in bytes.
+func (d *XmpDatum) Size() int64 {
+	if d.isClosed() {
+		return 0
+	}
+
+	result := int64(C.exiv2_xmp_datum_size(d.datum))
+	runtime.KeepAlive(d)
+
+	return result
+}
+
+// Int64 returns the n-th component of the datum value converted to an integer.
+func (d *XmpDatum) Int64(n int) (int64, error) {
+	if d.isClosed() {
+		return 0, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return 0, err
+	}
+
+	var ok C.int
+	result := int64(C.exiv2_xmp_datum_to_long(d.datum, C.long(n), &ok))
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return 0, ErrValueConversion
+	}
+
+	return result, nil
+}
+
+// Float64 returns the n-th component of the datum value converted to a float.
+func (d *XmpDatum) Float64(n int) (float64, error) {
+	if d.isClosed() {
+		return 0, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return 0, err
+	}
+
+	var ok C.int
+	result := float64(C.exiv2_xmp_datum_to_double(d.datum, C.long(n), &ok))
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return 0, ErrValueConversion
+	}
+
+	return result, nil
+}
+
+// Rational returns the n-th component of the datum value converted to a fraction.
+func (d *XmpDatum) Rational(n int) (Rational, error) {
+	if d.isClosed() {
+		return Rational{}, ErrClosed
+	}
+
+	if err := checkValueIndex(n, d.Count()); err != nil {
+		return Rational{}, err
+	}
+
+	var num, den C.longlong
+	ok := C.exiv2_xmp_datum_to_rational(d.datum, C.long(n), &num, &den)
+	runtime.KeepAlive(d)
+
+	if ok == 0 {
+		return Rational{}, ErrValueConversion
+	}
+
+	return Rational{int64(num), int64(den)}, nil
+}
+
+// Bytes returns the raw datum value. Multi-byte numbers are encoded in little-endian order.
+func (d *XmpDatum) Bytes() []byte {
+	if d.isClosed() {
+		return nil
+	}
+
+	var size C.long
+	ptr := C.exiv2_xmp_datum_to_bytes(d.datum, &size)
+	runtime.KeepAlive(d)
+
+	if ptr == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(ptr))
+
+	return C.GoBytes(unsafe.Pointer(ptr), C.int(size))
+}
+
+// Returns all XMP tags
+func (d *XmpData) AllTags() map[string]string {
+	keyValues := map[string]string{}
+	iter := d.Iterator()
+	defer iter.Close()
+
+	for iter.HasNext() {
+		datum := iter.Next()
+		if datum == nil {
+			break
+		}
+		keyValues[datum.Key()] = datum.String()
+		datum.Close()
+	}
+
+	return keyValues
+}
+
+// AllTagsInterpreted returns all XMP tags with their human-readable values, see XmpDatum.Interpreted
+func (d *XmpData) AllTagsInterpreted() map[string]string {
+	keyValues := map[string]string{}
+	iter := d.Iterator()
+	defer iter.Close()
+
+	for iter.HasNext() {
+		datum := iter.Next()
+		if datum == nil {
+			break
+		}
+		keyValues[datum.Key()] = datum.Interpreted()
+		datum.Close()
+	}
+
+	return keyValues
+}
+
+// Iterator returns a new XmpDatumIterator to iterate over all XMP data.
+func (d *XmpData) Iterator() *XmpDatumIterator {
+	if d.isClosed() {
+		return &XmpDatumIterator{data: d}
+	}
+
+	return makeXmpDatumIterator(d, C.exiv2_xmp_data_iterator(d.data))
+}
+
+// HasNext returns true as long as the iterator has another datum to deliver.
+func (i *XmpDatumIterator) HasNext() bool {
+	if i.isClosed() {
+		return false
+	}
+
+	return C.exiv2_xmp_data_iterator_has_next(i.iter) != 0
+}
+
+// Next returns the next XmpDatum of the iterator or nil if iterator has reached the end.
+func (i *XmpDatumIterator) Next() *XmpDatum {
+	if i.isClosed() {
+		return nil
+	}
+
+	return makeXmpDatum(i.data, C.exiv2_xmp_datum_iterator_next(i.iter))
+}
+
+func makeXmpDatumIterator(data *XmpData, cIter *C.Exiv2XmpDatumIterator) *XmpDatumIterator {
+	datum := &XmpDatumIterator{data, cIter}
+
+	runtime.SetFinalizer(datum, func(i *XmpDatumIterator) {
+		C.exiv2_xmp_datum_iterator_free(i.iter)
+	})
+
+	return datum
+}
+
+// Close frees the underlying C structure. It is safe to call Close more than once.
+func (i *XmpDatumIterator) Close() error {
+	if i.iter == nil {
+		return nil
+	}
+
+	runtime.SetFinalizer(i, nil)
+	C.exiv2_xmp_datum_iterator_free(i.iter)
+	i.iter = nil
+
+	return nil
+}
+
+func (i *XmpDatumIterator) isClosed() bool {
+	return i.iter == nil || i.data.isClosed()
+}
+
 func (i *Image) XmpStripKey(key string) error {
 	return i.StripKey(XMP, key)
 }